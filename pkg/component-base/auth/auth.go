@@ -1,38 +1,18 @@
-// Package auth encrypt and compare password string.
+// Package auth encrypt and compare password string, and sign and verify jwt tokens.
 package auth
 
-import (
-	"github.com/dgrijalva/jwt-go"
-	"golang.org/x/crypto/bcrypt"
-	"time"
-)
-
-// Encrypt the plain text with bcrypt.
+// Encrypt the plain text with DefaultPasswordHasher.
 func Encrypt(source string) (string, error) {
-	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(source), bcrypt.DefaultCost)
-	return string(hashedBytes), err
+	return DefaultPasswordHasher.Hash(source)
 }
 
-// Compare the encrypted text with the plain text if it's the same.
+// Compare the encrypted text with the plain text if it's the same, the encrypted text
+// may be hashed by any PasswordHasher of the package.
 func Compare(hashedPassword, password string) error {
-	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
-}
-
-// Sign issue a jwt token based on secretID, secretKey, iis and aud.
-func Sign(secretID string, secretKey string, iss string, aud string) string {
-	claims := jwt.MapClaims{
-		"exp": time.Now().Add(time.Minute).Unix(),
-		"iat": time.Now().Unix(),
-		"nbf": time.Now().Add(0).Unix(),
-		"aud": aud,
-		"iss": iss,
+	hasher, err := passwordHasherOf(hashedPassword)
+	if err != nil {
+		return err
 	}
 
-	// create a new token
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	token.Header["kid"] = secretID
-
-	// Sign the token with the specified secret.
-	tokenString, _ := token.SignedString([]byte(secretKey))
-	return tokenString
+	return hasher.Compare(hashedPassword, password)
 }
@@ -0,0 +1,48 @@
+package auth
+
+//go:generate codegen --type=int --output code_generated.go
+
+// Authentication errors of tokens.
+const (
+	// ErrTokenInvalid - 401: Token invalid.
+	ErrTokenInvalid int = iota + 100201
+
+	// ErrSignatureInvalid - 401: Signature is invalid.
+	ErrSignatureInvalid
+
+	// ErrExpired - 401: Token expired.
+	ErrExpired
+
+	// ErrTokenNotValidYet - 401: Token is not valid yet.
+	ErrTokenNotValidYet
+
+	// ErrInvalidIssuer - 401: Token issuer is invalid.
+	ErrInvalidIssuer
+
+	// ErrInvalidAudience - 401: Token audience is invalid.
+	ErrInvalidAudience
+
+	// ErrUnknownKey - 401: Token is signed with an unknown key.
+	ErrUnknownKey
+
+	// ErrTokenRevoked - 401: Token has been revoked.
+	ErrTokenRevoked
+)
+
+// Authentication errors of requests.
+const (
+	// ErrInvalidAuthHeader - 401: Authorization header is invalid.
+	ErrInvalidAuthHeader int = iota + 100221
+
+	// ErrRequestExpired - 401: Request timestamp is out of the allowed range.
+	ErrRequestExpired
+
+	// ErrNonceReused - 401: Request nonce has already been used.
+	ErrNonceReused
+
+	// ErrMissingAuthHeader - 401: Authorization header is missing.
+	ErrMissingAuthHeader
+
+	// ErrPasswordIncorrect - 401: Username or password is incorrect.
+	ErrPasswordIncorrect
+)
@@ -0,0 +1,22 @@
+// Code generated by "codegen --type=int --output code_generated.go"; DO NOT EDIT.
+
+package auth
+
+import "github.com/xs0910/iam/pkg/errors"
+
+// init register error codes defines in this source code to `github.com/xs0910/iam/pkg/errors`
+func init() {
+	errors.MustRegister(errors.NewCoder(ErrTokenInvalid, 401, "Token invalid", ""))
+	errors.MustRegister(errors.NewCoder(ErrSignatureInvalid, 401, "Signature is invalid", ""))
+	errors.MustRegister(errors.NewCoder(ErrExpired, 401, "Token expired", ""))
+	errors.MustRegister(errors.NewCoder(ErrTokenNotValidYet, 401, "Token is not valid yet", ""))
+	errors.MustRegister(errors.NewCoder(ErrInvalidIssuer, 401, "Token issuer is invalid", ""))
+	errors.MustRegister(errors.NewCoder(ErrInvalidAudience, 401, "Token audience is invalid", ""))
+	errors.MustRegister(errors.NewCoder(ErrUnknownKey, 401, "Token is signed with an unknown key", ""))
+	errors.MustRegister(errors.NewCoder(ErrTokenRevoked, 401, "Token has been revoked", ""))
+	errors.MustRegister(errors.NewCoder(ErrInvalidAuthHeader, 401, "Authorization header is invalid", ""))
+	errors.MustRegister(errors.NewCoder(ErrRequestExpired, 401, "Request timestamp is out of the allowed range", ""))
+	errors.MustRegister(errors.NewCoder(ErrNonceReused, 401, "Request nonce has already been used", ""))
+	errors.MustRegister(errors.NewCoder(ErrMissingAuthHeader, 401, "Authorization header is missing", ""))
+	errors.MustRegister(errors.NewCoder(ErrPasswordIncorrect, 401, "Username or password is incorrect", ""))
+}
@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// JSONWebKey is the public key of a JSON Web Key (RFC 7517).
+type JSONWebKey struct {
+	Kty string `json:"kty"`
+	Use string `json:"use,omitempty"`
+	Alg string `json:"alg,omitempty"`
+	Kid string `json:"kid"`
+
+	// RSA keys.
+	N string `json:"n,omitempty"`
+	E string `json:"e,omitempty"`
+
+	// EC and OKP keys.
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
+	Y   string `json:"y,omitempty"`
+}
+
+// JSONWebKeySet is a JWK Set, it implements KeyStore to verify tokens with the published keys.
+type JSONWebKeySet struct {
+	Keys []JSONWebKey `json:"keys"`
+}
+
+// NewJSONWebKey returns the JSON Web Key of the public key of key.
+// It returns an error for HMAC keys, secret keys must never be published.
+func NewJSONWebKey(key *SigningKey) (JSONWebKey, error) {
+	jwk := JSONWebKey{Use: "sig", Alg: key.Method.Alg(), Kid: key.ID}
+
+	switch k := key.Public().(type) {
+	case *rsa.PublicKey:
+		jwk.Kty = "RSA"
+		jwk.N = encodeBase64(k.N.Bytes())
+		jwk.E = encodeBase64(big.NewInt(int64(k.E)).Bytes())
+	case *ecdsa.PublicKey:
+		size := (k.Curve.Params().BitSize + 7) / 8
+		jwk.Kty = "EC"
+		jwk.Crv = k.Curve.Params().Name
+		jwk.X = encodeBase64(k.X.FillBytes(make([]byte, size)))
+		jwk.Y = encodeBase64(k.Y.FillBytes(make([]byte, size)))
+	case ed25519.PublicKey:
+		jwk.Kty = "OKP"
+		jwk.Crv = "Ed25519"
+		jwk.X = encodeBase64(k)
+	default:
+		return JSONWebKey{}, errors.Errorf("key %s of type %T cannot be published", key.ID, key.Key)
+	}
+
+	return jwk, nil
+}
+
+// PublicKey returns the public key of the JSON Web Key.
+func (k JSONWebKey) PublicKey() (interface{}, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBase64(k.N)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode n of key %s", k.Kid)
+		}
+		e, err := decodeBase64(k.E)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode e of key %s", k.Kid)
+		}
+		exponent := new(big.Int).SetBytes(e)
+		if !exponent.IsInt64() || exponent.Int64() > 1<<31-1 {
+			return nil, errors.Errorf("invalid exponent of key %s", k.Kid)
+		}
+
+		return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exponent.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, errors.Errorf("unsupported curve %s of key %s", k.Crv, k.Kid)
+		}
+		x, err := decodeBase64(k.X)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode x of key %s", k.Kid)
+		}
+		y, err := decodeBase64(k.Y)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode y of key %s", k.Kid)
+		}
+		publicKey := &ecdsa.PublicKey{Curve: curve, X: new(big.Int).SetBytes(x), Y: new(big.Int).SetBytes(y)}
+		if !curve.IsOnCurve(publicKey.X, publicKey.Y) {
+			return nil, errors.Errorf("invalid point of key %s", k.Kid)
+		}
+
+		return publicKey, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, errors.Errorf("unsupported curve %s of key %s", k.Crv, k.Kid)
+		}
+		x, err := decodeBase64(k.X)
+		if err != nil {
+			return nil, errors.Wrapf(err, "decode x of key %s", k.Kid)
+		}
+		if len(x) != ed25519.PublicKeySize {
+			return nil, errors.Errorf("invalid size of key %s", k.Kid)
+		}
+
+		return ed25519.PublicKey(x), nil
+	}
+
+	return nil, errors.Errorf("unsupported key type %s of key %s", k.Kty, k.Kid)
+}
+
+// Key returns the public key of kid, it implements KeyStore.
+func (s *JSONWebKeySet) Key(kid string) (interface{}, error) {
+	for _, key := range s.Keys {
+		if key.Kid == kid {
+			return key.PublicKey()
+		}
+	}
+
+	return nil, errors.Errorf("key %s not found", kid)
+}
+
+// JWKS returns the JWK Set of the public keys of the set, HMAC keys are skipped.
+func (s *KeySet) JWKS() *JSONWebKeySet {
+	jwks := &JSONWebKeySet{Keys: []JSONWebKey{}}
+	for _, key := range s.Keys() {
+		jwk, err := NewJSONWebKey(key)
+		if err != nil {
+			continue
+		}
+		jwks.Keys = append(jwks.Keys, jwk)
+	}
+
+	return jwks
+}
+
+// JWKSHandler returns a handler which publishes the JWK Set of keys, e.g. at
+// `/.well-known/jwks.json`. Clients may cache the response for maxAge.
+func JWKSHandler(keys *KeySet, maxAge time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			w.Header().Set("Allow", "GET, HEAD")
+			http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+			return
+		}
+
+		body, err := json.Marshal(keys.JWKS())
+		if err != nil {
+			http.Error(w, http.StatusText(http.StatusInternalServerError), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/jwk-set+json")
+		w.Header().Set("Cache-Control", "public, max-age="+strconv.Itoa(int(maxAge.Seconds())))
+		if r.Method == http.MethodGet {
+			_, _ = w.Write(body)
+		}
+	})
+}
+
+func encodeBase64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeBase64(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
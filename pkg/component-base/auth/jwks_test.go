@@ -0,0 +1,71 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestJWKSHandler(t *testing.T) {
+	keys := newTestSigningKeys(t)
+	secret, _ := NewSigningKey("secret", []byte("secret-key"))
+
+	set, _ := NewKeySet(keys...)
+	if err := set.Add(secret); err != nil {
+		t.Fatal(err)
+	}
+
+	rec := httptest.NewRecorder()
+	JWKSHandler(set, time.Hour).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/.well-known/jwks.json", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("got status %d", rec.Code)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=3600" {
+		t.Errorf("got Cache-Control %q", got)
+	}
+
+	var jwks JSONWebKeySet
+	if err := json.Unmarshal(rec.Body.Bytes(), &jwks); err != nil {
+		t.Fatal(err)
+	}
+	if len(jwks.Keys) != len(keys) {
+		t.Fatalf("got %d keys, want %d: secret keys must not be published", len(jwks.Keys), len(keys))
+	}
+
+	// downstream services verify tokens with the published keys only.
+	for _, key := range keys {
+		token, err := key.Sign(testClaims())
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := Verify(token, &jwks); err != nil {
+			t.Errorf("%s: Verify(): %v", key.Method.Alg(), err)
+		}
+	}
+
+	rec = httptest.NewRecorder()
+	JWKSHandler(set, time.Hour).ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/.well-known/jwks.json", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("got status %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+}
+
+func TestJSONWebKeyPublicKey(t *testing.T) {
+	tests := []JSONWebKey{
+		{Kty: "RSA", Kid: "rsa", N: "!", E: "AQAB"},
+		{Kty: "EC", Kid: "ec", Crv: "P-256", X: "AQ", Y: "AQ"},
+		{Kty: "EC", Kid: "ec", Crv: "secp256k1"},
+		{Kty: "OKP", Kid: "ed", Crv: "Ed25519", X: "AQ"},
+		{Kty: "oct", Kid: "secret"},
+	}
+
+	for _, jwk := range tests {
+		if _, err := jwk.PublicKey(); err == nil {
+			t.Errorf("PublicKey(%+v): expected error", jwk)
+		}
+	}
+}
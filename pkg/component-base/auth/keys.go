@@ -0,0 +1,237 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// SigningKey is a key used to sign tokens, identified by the key ID written in the `kid` header.
+type SigningKey struct {
+	// ID is the key ID of the key.
+	ID string
+	// Method is the signing method used with the key.
+	Method jwt.SigningMethod
+	// Key is the private key, or the secret key as []byte for HMAC.
+	Key interface{}
+}
+
+// NewSigningKey returns the signing key kid of key, which is an *rsa.PrivateKey (RS256),
+// an *ecdsa.PrivateKey (ES256, ES384 or ES512 depending on the curve), an ed25519.PrivateKey
+// (EdDSA) or a []byte secret key (HS256).
+func NewSigningKey(kid string, key interface{}) (*SigningKey, error) {
+	if kid == "" {
+		return nil, errors.New("key ID must not be empty")
+	}
+
+	var method jwt.SigningMethod
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		method = jwt.SigningMethodRS256
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			method = jwt.SigningMethodES256
+		case elliptic.P384():
+			method = jwt.SigningMethodES384
+		case elliptic.P521():
+			method = jwt.SigningMethodES512
+		default:
+			return nil, errors.Errorf("unsupported curve %s of key %s", k.Curve.Params().Name, kid)
+		}
+	case ed25519.PrivateKey:
+		method = SigningMethodEd25519
+	case []byte:
+		method = jwt.SigningMethodHS256
+	default:
+		return nil, errors.Errorf("unsupported key type %T of key %s", key, kid)
+	}
+
+	return &SigningKey{ID: kid, Method: method, Key: key}, nil
+}
+
+// ParseSigningKeyPEM returns the signing key kid of the PEM encoded private key in data,
+// which is a PKCS #1 RSA, SEC 1 EC or PKCS #8 private key.
+func ParseSigningKeyPEM(kid string, data []byte) (*SigningKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.Errorf("no PEM data found in key %s", kid)
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "EC PRIVATE KEY":
+		key, err = x509.ParseECPrivateKey(block.Bytes)
+	default:
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	}
+	if err != nil {
+		return nil, errors.Wrapf(err, "parse key %s", kid)
+	}
+
+	return NewSigningKey(kid, key)
+}
+
+// Public returns the key verifying the signatures of the key, which is the secret key for HMAC.
+func (k *SigningKey) Public() interface{} {
+	switch key := k.Key.(type) {
+	case *rsa.PrivateKey:
+		return &key.PublicKey
+	case *ecdsa.PrivateKey:
+		return &key.PublicKey
+	case ed25519.PrivateKey:
+		return key.Public()
+	}
+
+	return k.Key
+}
+
+// Sign signs claims with the key and sets the `kid` header of the token to the key ID.
+func (k *SigningKey) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.Method, claims)
+	token.Header["kid"] = k.ID
+
+	tokenString, err := token.SignedString(k.Key)
+	if err != nil {
+		return "", errors.Wrapf(err, "sign token with key %s", k.ID)
+	}
+
+	return tokenString, nil
+}
+
+// KeySet contains the signing keys of an issuer, it is safe for concurrent use.
+//
+// Tokens are signed with the active key, and verified with any key in the set, so keys
+// are rotated without invalidating the tokens already issued:
+//
+//	keys.Add(next)        // publish the next key before using it
+//	keys.Rotate(next.ID)  // sign new tokens with the next key
+//	keys.Remove(prev.ID)  // once the tokens signed with the previous key expired
+type KeySet struct {
+	mux    sync.RWMutex
+	keys   map[string]*SigningKey
+	order  []string
+	active string
+}
+
+// NewKeySet returns a KeySet containing keys, the first key is the active key.
+func NewKeySet(keys ...*SigningKey) (*KeySet, error) {
+	s := &KeySet{keys: map[string]*SigningKey{}}
+	for _, key := range keys {
+		if err := s.Add(key); err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Add adds key to the set for verification, it becomes the active key if the set was empty.
+func (s *KeySet) Add(key *SigningKey) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, ok := s.keys[key.ID]; ok {
+		return errors.Errorf("key %s already exists", key.ID)
+	}
+
+	s.keys[key.ID] = key
+	s.order = append(s.order, key.ID)
+	if s.active == "" {
+		s.active = key.ID
+	}
+
+	return nil
+}
+
+// Rotate makes the key kid of the set the active key.
+func (s *KeySet) Rotate(kid string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if _, ok := s.keys[kid]; !ok {
+		return errors.Errorf("key %s not found", kid)
+	}
+	s.active = kid
+
+	return nil
+}
+
+// Remove removes the key kid from the set, the tokens signed with it can no longer be verified.
+// The active key cannot be removed.
+func (s *KeySet) Remove(kid string) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	if kid == s.active {
+		return errors.Errorf("key %s is the active key", kid)
+	}
+	if _, ok := s.keys[kid]; !ok {
+		return errors.Errorf("key %s not found", kid)
+	}
+
+	delete(s.keys, kid)
+	for i, id := range s.order {
+		if id == kid {
+			s.order = append(s.order[:i], s.order[i+1:]...)
+			break
+		}
+	}
+
+	return nil
+}
+
+// Active returns the active key, or nil if the set is empty.
+func (s *KeySet) Active() *SigningKey {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return s.keys[s.active]
+}
+
+// Keys returns the keys of the set in the order they were added.
+func (s *KeySet) Keys() []*SigningKey {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	keys := make([]*SigningKey, 0, len(s.order))
+	for _, kid := range s.order {
+		keys = append(keys, s.keys[kid])
+	}
+
+	return keys
+}
+
+// Key returns the verification key of kid, it implements KeyStore.
+func (s *KeySet) Key(kid string) (interface{}, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, errors.Errorf("key %s not found", kid)
+	}
+
+	return key.Public(), nil
+}
+
+// Sign signs claims with the active key.
+func (s *KeySet) Sign(claims jwt.Claims) (string, error) {
+	key := s.Active()
+	if key == nil {
+		return "", errors.New("no signing key")
+	}
+
+	return key.Sign(claims)
+}
@@ -0,0 +1,152 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+func testClaims() jwt.MapClaims {
+	return jwt.MapClaims{
+		"exp": time.Now().Add(time.Minute).Unix(),
+		"iss": "iam-apiserver",
+	}
+}
+
+func newTestSigningKeys(t *testing.T) []*SigningKey {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, edKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var keys []*SigningKey
+	for kid, key := range map[string]interface{}{"rsa": rsaKey, "ec": ecKey, "ed": edKey} {
+		k, err := NewSigningKey(kid, key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+func TestSigningKey(t *testing.T) {
+	for _, key := range newTestSigningKeys(t) {
+		keys, err := NewKeySet(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		token, err := keys.Sign(testClaims())
+		if err != nil {
+			t.Fatalf("%s: Sign(): %v", key.Method.Alg(), err)
+		}
+
+		if _, err := Verify(token, keys); err != nil {
+			t.Errorf("%s: Verify(): %v", key.Method.Alg(), err)
+		}
+	}
+}
+
+func TestNewSigningKeyMethod(t *testing.T) {
+	ecKey, _ := ecdsa.GenerateKey(elliptic.P384(), rand.Reader)
+
+	key, err := NewSigningKey("ec", ecKey)
+	if err != nil || key.Method != jwt.SigningMethodES384 {
+		t.Errorf("NewSigningKey(): got (%v, %v), want ES384", key, err)
+	}
+
+	if _, err := NewSigningKey("ec", ecKey.PublicKey); err == nil {
+		t.Error("NewSigningKey(): expected error for a public key")
+	}
+}
+
+func TestParseSigningKeyPEM(t *testing.T) {
+	_, edKey, _ := ed25519.GenerateKey(rand.Reader)
+	der, err := x509.MarshalPKCS8PrivateKey(edKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := ParseSigningKeyPEM("ed", pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}))
+	if err != nil || key.Method != SigningMethodEd25519 {
+		t.Errorf("ParseSigningKeyPEM(): got (%v, %v), want EdDSA", key, err)
+	}
+
+	if _, err := ParseSigningKeyPEM("ed", []byte("garbage")); err == nil {
+		t.Error("ParseSigningKeyPEM(): expected error")
+	}
+}
+
+func TestKeySetRotate(t *testing.T) {
+	keys := newTestSigningKeys(t)
+	prev, next := keys[0], keys[1]
+
+	set, _ := NewKeySet(prev)
+	prevToken, _ := set.Sign(testClaims())
+
+	if err := set.Add(next); err != nil {
+		t.Fatal(err)
+	}
+	if err := set.Rotate(next.ID); err != nil {
+		t.Fatal(err)
+	}
+	if set.Active() != next {
+		t.Errorf("Active(): got %s, want %s", set.Active().ID, next.ID)
+	}
+
+	nextToken, _ := set.Sign(testClaims())
+	if _, kid, _ := Parse(nextToken); kid != next.ID {
+		t.Errorf("Sign(): got kid %s, want %s", kid, next.ID)
+	}
+
+	for _, token := range []string{prevToken, nextToken} {
+		if _, err := Verify(token, set); err != nil {
+			t.Errorf("Verify(): %v", err)
+		}
+	}
+
+	if err := set.Remove(next.ID); err == nil {
+		t.Error("Remove(): expected error for the active key")
+	}
+	if err := set.Remove(prev.ID); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Verify(prevToken, set); !errors.IsCode(err, ErrUnknownKey) {
+		t.Errorf("Verify(): got %v, want code %d", err, ErrUnknownKey)
+	}
+}
+
+func TestVerifyAlgorithmConfusion(t *testing.T) {
+	rsaKey, _ := rsa.GenerateKey(rand.Reader, 2048)
+	key, _ := NewSigningKey("rsa", rsaKey)
+	set, _ := NewKeySet(key)
+
+	// an HS256 token signed with the public key published for RS256.
+	der, _ := x509.MarshalPKIXPublicKey(&rsaKey.PublicKey)
+	public := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})
+	token := signHS256(t, "rsa", string(public), testClaims())
+
+	if _, err := Verify(token, set); !errors.IsCode(err, ErrSignatureInvalid) {
+		t.Errorf("Verify(): got %v, want code %d", err, ErrSignatureInvalid)
+	}
+}
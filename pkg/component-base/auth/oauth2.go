@@ -0,0 +1,316 @@
+package auth
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/xs0910/iam/pkg/component-base/util/sets"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// OAuth2 error codes of RFC 6749 section 5.2.
+const (
+	OAuth2InvalidRequest       = "invalid_request"
+	OAuth2InvalidClient        = "invalid_client"
+	OAuth2UnsupportedGrantType = "unsupported_grant_type"
+	OAuth2InvalidScope         = "invalid_scope"
+	OAuth2ServerError          = "server_error"
+)
+
+// OAuth2Error is an error response of RFC 6749 section 5.2.
+type OAuth2Error struct {
+	Code        string `json:"error"`
+	Description string `json:"error_description,omitempty"`
+
+	status int
+}
+
+// Error implements the error interface.
+func (e *OAuth2Error) Error() string {
+	return e.Code + ": " + e.Description
+}
+
+func newOAuth2Error(status int, code, description string) *OAuth2Error {
+	return &OAuth2Error{Code: code, Description: description, status: status}
+}
+
+// Client is an OAuth2 client, identified by its secretID and authenticated by its secretKey.
+type Client struct {
+	ID     string
+	Secret string
+	// Scopes are the scopes the client may request.
+	Scopes []string
+}
+
+// ClientStore looks up OAuth2 clients.
+type ClientStore interface {
+	// Client returns the client id, or nil without error if it does not exist.
+	Client(id string) (*Client, error)
+}
+
+// ClientStoreFunc is an adapter to allow the use of ordinary functions as ClientStore.
+type ClientStoreFunc func(id string) (*Client, error)
+
+// Client calls f(id).
+func (f ClientStoreFunc) Client(id string) (*Client, error) {
+	return f(id)
+}
+
+// TokenResponse is the successful response of RFC 6749 section 5.1.
+type TokenResponse struct {
+	AccessToken string `json:"access_token"`
+	TokenType   string `json:"token_type"`
+	ExpiresIn   int64  `json:"expires_in"`
+	Scope       string `json:"scope,omitempty"`
+}
+
+// IntrospectionResponse is the response of RFC 7662 section 2.2, only Active is set
+// if the token is not active.
+type IntrospectionResponse struct {
+	Active    bool   `json:"active"`
+	Scope     string `json:"scope,omitempty"`
+	ClientID  string `json:"client_id,omitempty"`
+	TokenType string `json:"token_type,omitempty"`
+	Exp       int64  `json:"exp,omitempty"`
+	Iat       int64  `json:"iat,omitempty"`
+	Nbf       int64  `json:"nbf,omitempty"`
+	Sub       string `json:"sub,omitempty"`
+	Aud       string `json:"aud,omitempty"`
+	Iss       string `json:"iss,omitempty"`
+	Jti       string `json:"jti,omitempty"`
+}
+
+// TokenServer issues access tokens to OAuth2 clients with the client_credentials grant,
+// and introspects them. The tokens are signed with the keys of the server, which the
+// clients never receive, so they cannot mint tokens with scopes they were not granted.
+// The resource servers verify the tokens with the same keys, or their JWKS if asymmetric.
+type TokenServer struct {
+	Clients  ClientStore
+	Keys     *KeySet
+	Issuer   string
+	Audience string
+
+	// TTL is the lifetime of the access tokens, it's an hour by default.
+	TTL time.Duration
+
+	// VerifyOptions are the additional options used to introspect tokens, e.g. WithRevocationStore.
+	VerifyOptions []VerifyOption
+}
+
+// NewTokenServer returns a TokenServer issuing tokens from iss for aud to clients, signed
+// with keys.
+func NewTokenServer(clients ClientStore, keys *KeySet, iss, aud string) *TokenServer {
+	return &TokenServer{
+		Clients:  clients,
+		Keys:     keys,
+		Issuer:   iss,
+		Audience: aud,
+		TTL:      time.Hour,
+	}
+}
+
+// Issue authenticates the client id with secret and issues an access token for scope,
+// a space-delimited list of scopes. All the scopes of the client are granted if scope is empty.
+// It returns an *OAuth2Error if the request is refused.
+func (s *TokenServer) Issue(id, secret, scope string) (*TokenResponse, error) {
+	client, err := s.authenticate(id, secret)
+	if err != nil {
+		return nil, err
+	}
+
+	allowed := sets.NewString(client.Scopes...)
+	granted := allowed
+	if scope != "" {
+		granted = sets.NewString(strings.Fields(scope)...)
+		if !allowed.IsSuperset(granted) {
+			return nil, newOAuth2Error(http.StatusBadRequest, OAuth2InvalidScope,
+				"scopes not allowed: "+strings.Join(granted.Difference(allowed).List(), " "))
+		}
+	}
+	grantedScope := strings.Join(granted.List(), " ")
+
+	claims, err := NewClaims(s.Issuer, s.Audience,
+		WithTTL(s.TTL),
+		WithSubject(client.ID),
+		WithClaims(map[string]interface{}{"client_id": client.ID, "scope": grantedScope}))
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := s.Keys.Sign(claims)
+	if err != nil {
+		return nil, errors.Wrapf(err, "sign token of client %s", client.ID)
+	}
+
+	return &TokenResponse{
+		AccessToken: token,
+		TokenType:   "Bearer",
+		ExpiresIn:   int64(s.TTL / time.Second),
+		Scope:       grantedScope,
+	}, nil
+}
+
+// Introspect returns the introspection response of token.
+func (s *TokenServer) Introspect(token string) *IntrospectionResponse {
+	claims, err := Verify(token, s.Keys, append([]VerifyOption{
+		WithIssuer(s.Issuer),
+		WithAudience(s.Audience),
+	}, s.VerifyOptions...)...)
+	if err != nil {
+		return &IntrospectionResponse{Active: false}
+	}
+
+	resp := &IntrospectionResponse{Active: true, TokenType: "Bearer"}
+	resp.Scope, _ = claims["scope"].(string)
+	resp.ClientID, _ = claims["client_id"].(string)
+	resp.Sub, _ = claims["sub"].(string)
+	resp.Aud, _ = claims["aud"].(string)
+	resp.Iss, _ = claims["iss"].(string)
+	resp.Jti, _ = claims["jti"].(string)
+	if exp, ok, _ := numericDate(claims, "exp"); ok {
+		resp.Exp = exp.Unix()
+	}
+	if iat, ok, _ := numericDate(claims, "iat"); ok {
+		resp.Iat = iat.Unix()
+	}
+	if nbf, ok, _ := numericDate(claims, "nbf"); ok {
+		resp.Nbf = nbf.Unix()
+	}
+
+	return resp
+}
+
+// Scopes returns the scopes granted to the token of claims.
+func Scopes(claims jwt.MapClaims) []string {
+	scope, _ := claims["scope"].(string)
+	return strings.Fields(scope)
+}
+
+// authenticate returns the client id if secret is its secret.
+func (s *TokenServer) authenticate(id, secret string) (*Client, error) {
+	if id == "" {
+		return nil, newOAuth2Error(http.StatusUnauthorized, OAuth2InvalidClient, "missing client credentials")
+	}
+
+	client, err := s.Clients.Client(id)
+	if err != nil {
+		return nil, errors.Wrapf(err, "look up client %s", id)
+	}
+	if client == nil || subtle.ConstantTimeCompare([]byte(client.Secret), []byte(secret)) != 1 {
+		return nil, newOAuth2Error(http.StatusUnauthorized, OAuth2InvalidClient, "client authentication failed")
+	}
+
+	return client, nil
+}
+
+// TokenHandler returns the handler of the token endpoint of RFC 6749 section 3.2, which
+// only supports the client_credentials grant. The clients authenticate with HTTP Basic,
+// or with the client_id and client_secret parameters.
+func (s *TokenServer) TokenHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, form, err := s.parseRequest(r)
+		if err != nil {
+			writeOAuth2Error(w, err)
+			return
+		}
+
+		if grantType := form.Get("grant_type"); grantType != "client_credentials" {
+			if grantType == "" {
+				writeOAuth2Error(w, newOAuth2Error(http.StatusBadRequest, OAuth2InvalidRequest, "missing grant_type"))
+			} else {
+				writeOAuth2Error(w, newOAuth2Error(http.StatusBadRequest, OAuth2UnsupportedGrantType, "unsupported grant_type "+grantType))
+			}
+			return
+		}
+
+		resp, err := s.Issue(id, secret, form.Get("scope"))
+		if err != nil {
+			writeOAuth2Error(w, err)
+			return
+		}
+
+		writeOAuth2JSON(w, http.StatusOK, resp)
+	})
+}
+
+// IntrospectionHandler returns the handler of the introspection endpoint of RFC 7662. The
+// resource servers authenticate as clients like on the token endpoint.
+func (s *TokenServer) IntrospectionHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id, secret, form, err := s.parseRequest(r)
+		if err != nil {
+			writeOAuth2Error(w, err)
+			return
+		}
+
+		if _, err := s.authenticate(id, secret); err != nil {
+			writeOAuth2Error(w, err)
+			return
+		}
+
+		token := form.Get("token")
+		if token == "" {
+			writeOAuth2Error(w, newOAuth2Error(http.StatusBadRequest, OAuth2InvalidRequest, "missing token"))
+			return
+		}
+
+		writeOAuth2JSON(w, http.StatusOK, s.Introspect(token))
+	})
+}
+
+// parseRequest returns the client credentials and the form of the POST request r.
+func (s *TokenServer) parseRequest(r *http.Request) (id, secret string, form url.Values, err error) {
+	if r.Method != http.MethodPost {
+		return "", "", nil, newOAuth2Error(http.StatusMethodNotAllowed, OAuth2InvalidRequest, "method must be POST")
+	}
+	if err := r.ParseForm(); err != nil {
+		return "", "", nil, newOAuth2Error(http.StatusBadRequest, OAuth2InvalidRequest, "malformed form")
+	}
+
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		return r.PostForm.Get("client_id"), r.PostForm.Get("client_secret"), r.PostForm, nil
+	}
+
+	// the credentials are form-urlencoded before being encoded with HTTP Basic, see RFC 6749 section 2.3.1.
+	if id, err = url.QueryUnescape(id); err == nil {
+		secret, err = url.QueryUnescape(secret)
+	}
+	if err != nil {
+		return "", "", nil, newOAuth2Error(http.StatusUnauthorized, OAuth2InvalidClient, "malformed client credentials")
+	}
+
+	return id, secret, r.PostForm, nil
+}
+
+// writeOAuth2Error writes err as an RFC 6749 error response, the errors which are not
+// *OAuth2Error are server errors.
+func writeOAuth2Error(w http.ResponseWriter, err error) {
+	var oauthErr *OAuth2Error
+	if !errors.As(err, &oauthErr) {
+		oauthErr = newOAuth2Error(http.StatusInternalServerError, OAuth2ServerError, "")
+	}
+
+	if oauthErr.status == http.StatusUnauthorized {
+		w.Header().Set("WWW-Authenticate", `Basic realm="oauth2"`)
+	}
+	if oauthErr.status == http.StatusMethodNotAllowed {
+		w.Header().Set("Allow", http.MethodPost)
+	}
+
+	writeOAuth2JSON(w, oauthErr.status, oauthErr)
+}
+
+func writeOAuth2JSON(w http.ResponseWriter, status int, obj interface{}) {
+	w.Header().Set("Content-Type", "application/json;charset=UTF-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set("Pragma", "no-cache")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(obj)
+}
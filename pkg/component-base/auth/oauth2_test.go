@@ -0,0 +1,145 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var testServerKeys = func() *KeySet {
+	key, _ := NewSigningKey("server-key", []byte("server-secret"))
+	keys, _ := NewKeySet(key)
+
+	return keys
+}()
+
+func newTestTokenServer() *TokenServer {
+	clients := map[string]*Client{
+		"client-id": {ID: "client-id", Secret: "client-secret", Scopes: []string{"users:read", "users:write"}},
+		"resource":  {ID: "resource", Secret: "resource-secret"},
+	}
+
+	return NewTokenServer(ClientStoreFunc(func(id string) (*Client, error) {
+		return clients[id], nil
+	}), testServerKeys, "iam-apiserver", "iam.authz.example.com")
+}
+
+func postForm(handler http.Handler, form url.Values, basic ...string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/oauth2/token", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if len(basic) == 2 {
+		req.SetBasicAuth(basic[0], basic[1])
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestTokenHandler(t *testing.T) {
+	handler := newTestTokenServer().TokenHandler()
+
+	tests := []struct {
+		name       string
+		form       url.Values
+		basic      []string
+		wantStatus int
+		wantError  string
+		wantScope  string
+	}{
+		{"basic", url.Values{"grant_type": {"client_credentials"}}, []string{"client-id", "client-secret"}, http.StatusOK, "", "users:read users:write"},
+		{"form credentials", url.Values{
+			"grant_type":    {"client_credentials"},
+			"client_id":     {"client-id"},
+			"client_secret": {"client-secret"},
+			"scope":         {"users:read"},
+		}, nil, http.StatusOK, "", "users:read"},
+		{"wrong secret", url.Values{"grant_type": {"client_credentials"}}, []string{"client-id", "wrong"}, http.StatusUnauthorized, OAuth2InvalidClient, ""},
+		{"unknown client", url.Values{"grant_type": {"client_credentials"}}, []string{"unknown", "client-secret"}, http.StatusUnauthorized, OAuth2InvalidClient, ""},
+		{"missing credentials", url.Values{"grant_type": {"client_credentials"}}, nil, http.StatusUnauthorized, OAuth2InvalidClient, ""},
+		{"scope not allowed", url.Values{"grant_type": {"client_credentials"}, "scope": {"users:read users:delete"}}, []string{"client-id", "client-secret"}, http.StatusBadRequest, OAuth2InvalidScope, ""},
+		{"password grant", url.Values{"grant_type": {"password"}}, []string{"client-id", "client-secret"}, http.StatusBadRequest, OAuth2UnsupportedGrantType, ""},
+		{"missing grant", url.Values{}, []string{"client-id", "client-secret"}, http.StatusBadRequest, OAuth2InvalidRequest, ""},
+	}
+
+	for _, tt := range tests {
+		w := postForm(handler, tt.form, tt.basic...)
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: got status %d, want %d: %s", tt.name, w.Code, tt.wantStatus, w.Body.String())
+			continue
+		}
+		if w.Header().Get("Cache-Control") != "no-store" {
+			t.Errorf("%s: got Cache-Control %q", tt.name, w.Header().Get("Cache-Control"))
+		}
+
+		if tt.wantError != "" {
+			var resp OAuth2Error
+			if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Code != tt.wantError {
+				t.Errorf("%s: got %s, want error %s", tt.name, w.Body.String(), tt.wantError)
+			}
+			continue
+		}
+
+		var resp TokenResponse
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.TokenType != "Bearer" || resp.ExpiresIn != 3600 || resp.Scope != tt.wantScope || resp.AccessToken == "" {
+			t.Errorf("%s: got %+v", tt.name, resp)
+		}
+	}
+}
+
+func TestIntrospectionHandler(t *testing.T) {
+	server := newTestTokenServer()
+
+	token, err := server.Issue("client-id", "client-secret", "users:read")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	introspect := func(token string, basic ...string) (*httptest.ResponseRecorder, IntrospectionResponse) {
+		w := postForm(server.IntrospectionHandler(), url.Values{"token": {token}}, basic...)
+
+		var resp IntrospectionResponse
+		_ = json.Unmarshal(w.Body.Bytes(), &resp)
+
+		return w, resp
+	}
+
+	w, resp := introspect(token.AccessToken, "resource", "resource-secret")
+	if w.Code != http.StatusOK || !resp.Active || resp.ClientID != "client-id" || resp.Scope != "users:read" || resp.Exp == 0 {
+		t.Errorf("got %d %s", w.Code, w.Body.String())
+	}
+
+	claims, err := Verify(token.AccessToken, testServerKeys)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := Scopes(claims); !reflect.DeepEqual(got, []string{"users:read"}) {
+		t.Errorf("Scopes(): got %v", got)
+	}
+
+	if _, resp := introspect(token.AccessToken+"x", "resource", "resource-secret"); resp.Active || resp.ClientID != "" {
+		t.Errorf("got active response %+v for an invalid token", resp)
+	}
+
+	// a token signed by a client with its own secret is not active, whatever its scopes.
+	forged, err := Sign("client-id", "client-secret", "iam-apiserver", "iam.authz.example.com",
+		WithClaims(map[string]interface{}{"client_id": "client-id", "scope": "users:admin"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, resp := introspect(forged, "resource", "resource-secret"); resp.Active {
+		t.Errorf("got active response %+v for a token signed by a client", resp)
+	}
+
+	if w, _ := introspect(token.AccessToken); w.Code != http.StatusUnauthorized {
+		t.Errorf("got status %d, want %d for an unauthenticated caller", w.Code, http.StatusUnauthorized)
+	}
+}
@@ -0,0 +1,220 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/xs0910/iam/pkg/component-base/validation"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// ErrMismatchedHashAndPassword is returned by Compare when a password does not match its hash.
+var ErrMismatchedHashAndPassword = bcrypt.ErrMismatchedHashAndPassword
+
+// PasswordHasher hashes passwords into self-describing hashes, which contain the algorithm
+// and its parameters as a prefix, e.g. `$2a$10$` for bcrypt.
+type PasswordHasher interface {
+	// Hash returns the hash of password.
+	Hash(password string) (string, error)
+	// Compare returns nil if hashedPassword is the hash of password, it understands the hashes
+	// of the algorithm of the hasher whatever their parameters are.
+	Compare(hashedPassword, password string) error
+	// NeedsRehash reports whether hashedPassword is not hashed with the algorithm and the
+	// parameters of the hasher, so it should be hashed again.
+	NeedsRehash(hashedPassword string) bool
+}
+
+// DefaultPasswordHasher is the hasher used by Encrypt and NeedsRehash.
+var DefaultPasswordHasher PasswordHasher = &BcryptHasher{Cost: bcrypt.DefaultCost}
+
+// NeedsRehash reports whether hashedPassword should be hashed again by DefaultPasswordHasher.
+func NeedsRehash(hashedPassword string) bool {
+	return DefaultPasswordHasher.NeedsRehash(hashedPassword)
+}
+
+// NewPasswordPolicy returns the default password policy, see validation.NewPasswordPolicy,
+// which checks the password history with Compare.
+func NewPasswordPolicy() *validation.PasswordPolicy {
+	p := validation.NewPasswordPolicy()
+	p.CompareHistory = Compare
+
+	return p
+}
+
+// CompareAndRehash compares hashedPassword with password, and returns the hash of password
+// by DefaultPasswordHasher if hashedPassword needs rehash, or "" otherwise. It's used to
+// upgrade the legacy hashes on login:
+//
+//	newHash, err := auth.CompareAndRehash(user.Password, password)
+//	if err != nil {
+//		return err
+//	}
+//	if newHash != "" {
+//		user.Password = newHash // and save the user
+//	}
+func CompareAndRehash(hashedPassword, password string) (string, error) {
+	if err := Compare(hashedPassword, password); err != nil {
+		return "", err
+	}
+
+	if !NeedsRehash(hashedPassword) {
+		return "", nil
+	}
+
+	return DefaultPasswordHasher.Hash(password)
+}
+
+// passwordHasherOf returns the hasher understanding hashedPassword by its prefix.
+func passwordHasherOf(hashedPassword string) (PasswordHasher, error) {
+	switch {
+	case strings.HasPrefix(hashedPassword, argon2idPrefix):
+		return &Argon2idHasher{}, nil
+	case strings.HasPrefix(hashedPassword, "$2a$"),
+		strings.HasPrefix(hashedPassword, "$2b$"),
+		strings.HasPrefix(hashedPassword, "$2y$"):
+		return &BcryptHasher{}, nil
+	}
+
+	return nil, errors.New("unknown password hash algorithm")
+}
+
+// BcryptHasher is a PasswordHasher using bcrypt.
+type BcryptHasher struct {
+	// Cost is the bcrypt cost, bcrypt.DefaultCost is used if it's less than bcrypt.MinCost.
+	Cost int
+}
+
+func (h *BcryptHasher) cost() int {
+	if h.Cost < bcrypt.MinCost {
+		return bcrypt.DefaultCost
+	}
+
+	return h.Cost
+}
+
+// Hash returns the bcrypt hash of password.
+func (h *BcryptHasher) Hash(password string) (string, error) {
+	hashedBytes, err := bcrypt.GenerateFromPassword([]byte(password), h.cost())
+	return string(hashedBytes), err
+}
+
+// Compare returns nil if the bcrypt hash hashedPassword is the hash of password.
+func (h *BcryptHasher) Compare(hashedPassword, password string) error {
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+}
+
+// NeedsRehash reports whether hashedPassword is not a bcrypt hash of the cost of the hasher.
+func (h *BcryptHasher) NeedsRehash(hashedPassword string) bool {
+	cost, err := bcrypt.Cost([]byte(hashedPassword))
+	return err != nil || cost != h.cost()
+}
+
+const argon2idPrefix = "$argon2id$"
+
+// Argon2idHasher is a PasswordHasher using argon2id, the hashes are encoded in the PHC
+// string format: `$argon2id$v=19$m=65536,t=1,p=4$<salt>$<key>`.
+type Argon2idHasher struct {
+	// Time is the number of passes over the memory.
+	Time uint32
+	// Memory is the size of the memory in KiB.
+	Memory uint32
+	// Threads is the number of threads.
+	Threads uint8
+	// SaltLength is the length of the random salt in bytes.
+	SaltLength uint32
+	// KeyLength is the length of the derived key in bytes.
+	KeyLength uint32
+}
+
+// NewArgon2idHasher returns an Argon2idHasher with the parameters recommended by
+// golang.org/x/crypto/argon2.
+func NewArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{
+		Time:       1,
+		Memory:     64 * 1024,
+		Threads:    4,
+		SaltLength: 16,
+		KeyLength:  32,
+	}
+}
+
+// Hash returns the argon2id hash of password.
+func (h *Argon2idHasher) Hash(password string) (string, error) {
+	if h.Time == 0 || h.Memory == 0 || h.Threads == 0 || h.SaltLength == 0 || h.KeyLength == 0 {
+		return "", errors.Errorf("invalid argon2id parameters %+v", *h)
+	}
+
+	salt := make([]byte, h.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", errors.Wrap(err, "generate salt")
+	}
+
+	key := argon2.IDKey([]byte(password), salt, h.Time, h.Memory, h.Threads, h.KeyLength)
+
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		h.Memory, h.Time, h.Threads,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Compare returns nil if the argon2id hash hashedPassword is the hash of password.
+func (h *Argon2idHasher) Compare(hashedPassword, password string) error {
+	params, salt, key, err := decodeArgon2id(hashedPassword)
+	if err != nil {
+		return err
+	}
+
+	other := argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLength)
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return ErrMismatchedHashAndPassword
+	}
+
+	return nil
+}
+
+// NeedsRehash reports whether hashedPassword is not an argon2id hash of the parameters of the hasher.
+func (h *Argon2idHasher) NeedsRehash(hashedPassword string) bool {
+	params, _, _, err := decodeArgon2id(hashedPassword)
+	return err != nil || *params != *h
+}
+
+// decodeArgon2id returns the parameters, the salt and the key of the argon2id hash hashedPassword.
+func decodeArgon2id(hashedPassword string) (*Argon2idHasher, []byte, []byte, error) {
+	parts := strings.Split(hashedPassword, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return nil, nil, nil, errors.New("invalid argon2id hash")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return nil, nil, nil, errors.Errorf("unsupported argon2id version %s", parts[2])
+	}
+
+	params := &Argon2idHasher{}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return nil, nil, nil, errors.Wrap(err, "invalid argon2id parameters")
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "invalid argon2id salt")
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil {
+		return nil, nil, nil, errors.Wrap(err, "invalid argon2id key")
+	}
+	if params.Time == 0 || params.Threads == 0 || len(key) == 0 {
+		return nil, nil, nil, errors.New("invalid argon2id parameters")
+	}
+
+	params.SaltLength = uint32(len(salt))
+	params.KeyLength = uint32(len(key))
+
+	return params, salt, key, nil
+}
@@ -0,0 +1,103 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/xs0910/iam/pkg/component-base/validation/field"
+)
+
+func testArgon2idHasher() *Argon2idHasher {
+	return &Argon2idHasher{Time: 1, Memory: 1024, Threads: 1, SaltLength: 16, KeyLength: 32}
+}
+
+func TestPasswordHasher(t *testing.T) {
+	hashers := map[string]PasswordHasher{
+		"$2a$04$":    &BcryptHasher{Cost: bcrypt.MinCost},
+		"$argon2id$": testArgon2idHasher(),
+	}
+
+	for prefix, hasher := range hashers {
+		hashed, err := hasher.Hash("Passw0rd!")
+		if err != nil {
+			t.Fatalf("%s: Hash(): %v", prefix, err)
+		}
+		if !strings.HasPrefix(hashed, prefix) {
+			t.Errorf("Hash(): got %s, want prefix %s", hashed, prefix)
+		}
+
+		if err := Compare(hashed, "Passw0rd!"); err != nil {
+			t.Errorf("%s: Compare(): %v", prefix, err)
+		}
+		if err := Compare(hashed, "password"); err != ErrMismatchedHashAndPassword {
+			t.Errorf("%s: Compare(): got %v, want %v", prefix, err, ErrMismatchedHashAndPassword)
+		}
+
+		if hasher.NeedsRehash(hashed) {
+			t.Errorf("%s: NeedsRehash(): got true for its own hash", prefix)
+		}
+	}
+}
+
+func TestNeedsRehash(t *testing.T) {
+	legacy, _ := (&BcryptHasher{Cost: bcrypt.MinCost}).Hash("Passw0rd!")
+	argon, _ := testArgon2idHasher().Hash("Passw0rd!")
+
+	tests := []struct {
+		hasher PasswordHasher
+		hashed string
+		want   bool
+	}{
+		{&BcryptHasher{Cost: bcrypt.MinCost + 1}, legacy, true},
+		{&BcryptHasher{Cost: bcrypt.MinCost}, argon, true},
+		{testArgon2idHasher(), legacy, true},
+		{&Argon2idHasher{Time: 2, Memory: 1024, Threads: 1, SaltLength: 16, KeyLength: 32}, argon, true},
+		{testArgon2idHasher(), "$argon2id$v=19$m=1024,t=1,p=1$garbage", true},
+	}
+
+	for i, tt := range tests {
+		if got := tt.hasher.NeedsRehash(tt.hashed); got != tt.want {
+			t.Errorf("%d: NeedsRehash(): got %v, want %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestCompareAndRehash(t *testing.T) {
+	defer func(h PasswordHasher) { DefaultPasswordHasher = h }(DefaultPasswordHasher)
+	DefaultPasswordHasher = testArgon2idHasher()
+
+	legacy, _ := (&BcryptHasher{Cost: bcrypt.MinCost}).Hash("Passw0rd!")
+
+	if _, err := CompareAndRehash(legacy, "password"); err == nil {
+		t.Fatal("CompareAndRehash(): expected error")
+	}
+
+	upgraded, err := CompareAndRehash(legacy, "Passw0rd!")
+	if err != nil || !strings.HasPrefix(upgraded, "$argon2id$") {
+		t.Fatalf("CompareAndRehash(): got (%s, %v)", upgraded, err)
+	}
+
+	if again, err := CompareAndRehash(upgraded, "Passw0rd!"); err != nil || again != "" {
+		t.Errorf("CompareAndRehash(): got (%s, %v), want no rehash", again, err)
+	}
+}
+
+func TestCompareUnknownHash(t *testing.T) {
+	if err := Compare("plain", "plain"); err == nil {
+		t.Error("Compare(): expected error")
+	}
+}
+
+func TestNewPasswordPolicyHistory(t *testing.T) {
+	hash, err := testArgon2idHasher().Hash("Xk9#mPq2")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	errs := NewPasswordPolicy().Validate(field.NewPath("password"), "Xk9#mPq2", nil, []string{hash})
+	if len(errs) != 1 || errs[0].Detail != "must not be a previously used password" {
+		t.Errorf("got errors %v", errs)
+	}
+}
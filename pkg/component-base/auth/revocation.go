@@ -0,0 +1,197 @@
+package auth
+
+import (
+	"sync"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"k8s.io/klog/v2"
+
+	metav1 "github.com/xs0910/iam/pkg/component-base/meta/v1"
+	"github.com/xs0910/iam/pkg/component-base/util/idutil"
+	"github.com/xs0910/iam/pkg/component-base/util/wait"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// RevocationStore stores the `jti` of the revoked tokens until the tokens expire.
+type RevocationStore interface {
+	// Revoke revokes the token jti, which expires at exp.
+	Revoke(jti string, exp time.Time) error
+	// IsRevoked reports whether the token jti is revoked.
+	IsRevoked(jti string) (bool, error)
+	// Prune removes the revoked tokens which expired before before.
+	Prune(before time.Time) error
+}
+
+// WithRevocationStore rejects the tokens revoked in store, and the tokens without a `jti` claim.
+func WithRevocationStore(store RevocationStore) VerifyOption {
+	return func(v *Verifier) { v.revocations = store }
+}
+
+// Revoke revokes the token of claims, which are usually returned by Verify, in store.
+func Revoke(store RevocationStore, claims jwt.MapClaims) error {
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return errors.WithCode(ErrTokenInvalid, "missing jti claim")
+	}
+
+	exp, ok, err := numericDate(claims, "exp")
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.WithCode(ErrTokenInvalid, "missing exp claim, token %s cannot be revoked", jti)
+	}
+
+	return store.Revoke(jti, exp)
+}
+
+// checkRevoked rejects the token of claims if it's revoked.
+func (v *Verifier) checkRevoked(claims jwt.MapClaims) error {
+	jti, _ := claims["jti"].(string)
+	if jti == "" {
+		return errors.WithCode(ErrTokenInvalid, "missing jti claim")
+	}
+
+	revoked, err := v.revocations.IsRevoked(jti)
+	if err != nil {
+		return errors.Wrapf(err, "check revocation of token %s", jti)
+	}
+	if revoked {
+		return errors.WithCode(ErrTokenRevoked, "token %s has been revoked", jti)
+	}
+
+	return nil
+}
+
+// RunPruner prunes the revoked tokens of store every period until stopCh is closed. The
+// tokens are kept for grace after they expired, which must cover the leeway used to verify
+// tokens. It blocks, so it's usually run in a goroutine.
+func RunPruner(store RevocationStore, period, grace time.Duration, stopCh <-chan struct{}) {
+	wait.Until(func() {
+		if err := store.Prune(time.Now().Add(-grace)); err != nil {
+			klog.Errorf("Failed to prune revoked tokens: %v", err)
+		}
+	}, period, stopCh)
+}
+
+// MemoryRevocationStore is a RevocationStore in memory, it is safe for concurrent use.
+type MemoryRevocationStore struct {
+	mux     sync.RWMutex
+	revoked map[string]time.Time
+}
+
+var _ RevocationStore = &MemoryRevocationStore{}
+
+// NewMemoryRevocationStore returns an empty MemoryRevocationStore.
+func NewMemoryRevocationStore() *MemoryRevocationStore {
+	return &MemoryRevocationStore{revoked: map[string]time.Time{}}
+}
+
+// Revoke revokes the token jti until exp.
+func (s *MemoryRevocationStore) Revoke(jti string, exp time.Time) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	s.revoked[jti] = exp
+	return nil
+}
+
+// IsRevoked reports whether the token jti is revoked.
+func (s *MemoryRevocationStore) IsRevoked(jti string) (bool, error) {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	_, ok := s.revoked[jti]
+	return ok, nil
+}
+
+// Prune removes the revoked tokens which expired before before.
+func (s *MemoryRevocationStore) Prune(before time.Time) error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	for jti, exp := range s.revoked {
+		if exp.Before(before) {
+			delete(s.revoked, jti)
+		}
+	}
+
+	return nil
+}
+
+// Len returns the number of revoked tokens in the store.
+func (s *MemoryRevocationStore) Len() int {
+	s.mux.RLock()
+	defer s.mux.RUnlock()
+
+	return len(s.revoked)
+}
+
+// RevokedToken is the database record of a revoked token, its name is the `jti` of the token.
+type RevokedToken struct {
+	// Standard object's metadata.
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	// JTI is the `jti` of the token, it's unique so that a token is revoked once.
+	JTI string `json:"jti" gorm:"column:jti;type:varchar(64);not null;uniqueIndex:idx_jti"`
+
+	// ExpiresAt is the expiration time of the token, the record is pruned after it.
+	ExpiresAt time.Time `json:"expiresAt" gorm:"column:expiresAt;index:idx_expiresAt"`
+}
+
+// TableName maps to mysql table name.
+func (t *RevokedToken) TableName() string {
+	return "revoked_token"
+}
+
+// GormRevocationStore is a RevocationStore in the database, the table is created by
+// migrating RevokedToken.
+type GormRevocationStore struct {
+	db *gorm.DB
+}
+
+var _ RevocationStore = &GormRevocationStore{}
+
+// NewGormRevocationStore returns a GormRevocationStore storing the revoked tokens in db.
+func NewGormRevocationStore(db *gorm.DB) *GormRevocationStore {
+	return &GormRevocationStore{db: db}
+}
+
+// Revoke revokes the token jti until exp, revoking a token twice is not an error.
+func (s *GormRevocationStore) Revoke(jti string, exp time.Time) error {
+	token := &RevokedToken{
+		ObjectMeta: metav1.ObjectMeta{InstanceID: idutil.GetUUID36("revoked-"), Name: jti},
+		JTI:        jti,
+		ExpiresAt:  exp,
+	}
+	err := s.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "jti"}}, DoNothing: true}).
+		Create(token).Error
+	if err != nil {
+		return errors.Wrapf(err, "revoke token %s", jti)
+	}
+
+	return nil
+}
+
+// IsRevoked reports whether the token jti is revoked.
+func (s *GormRevocationStore) IsRevoked(jti string) (bool, error) {
+	var count int64
+	if err := s.db.Model(&RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, errors.Wrapf(err, "look up revoked token %s", jti)
+	}
+
+	return count > 0, nil
+}
+
+// Prune deletes the revoked tokens which expired before before.
+func (s *GormRevocationStore) Prune(before time.Time) error {
+	err := s.db.Unscoped().Where("expiresAt < ?", before).Delete(&RevokedToken{}).Error
+	if err != nil {
+		return errors.Wrap(err, "prune revoked tokens")
+	}
+
+	return nil
+}
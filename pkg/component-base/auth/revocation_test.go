@@ -0,0 +1,124 @@
+package auth
+
+import (
+	"testing"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return db
+}
+
+func testRevocationStore(t *testing.T, store RevocationStore) {
+	token, err := Sign("secret-id", "secret-key", "iam-apiserver", "iam.authz.example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	v := NewVerifier(testKeys, WithRevocationStore(store))
+	claims, err := v.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify(): %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := Revoke(store, claims); err != nil {
+			t.Fatalf("Revoke(): %v", err)
+		}
+	}
+
+	if _, err := v.Verify(token); !errors.IsCode(err, ErrTokenRevoked) {
+		t.Errorf("Verify(): got %v, want code %d", err, ErrTokenRevoked)
+	}
+
+	// the token is kept until it expires.
+	if err := store.Prune(time.Now()); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := store.IsRevoked(claims["jti"].(string)); !revoked {
+		t.Error("IsRevoked(): token pruned before it expired")
+	}
+
+	if err := store.Prune(time.Now().Add(time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if revoked, _ := store.IsRevoked(claims["jti"].(string)); revoked {
+		t.Error("IsRevoked(): expired token not pruned")
+	}
+
+	if err := Revoke(store, map[string]interface{}{"exp": 0}); !errors.IsCode(err, ErrTokenInvalid) {
+		t.Errorf("Revoke(): got %v, want code %d", err, ErrTokenInvalid)
+	}
+}
+
+func TestMemoryRevocationStore(t *testing.T) {
+	testRevocationStore(t, NewMemoryRevocationStore())
+}
+
+func TestGormRevocationStore(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&RevokedToken{}); err != nil {
+		t.Fatal(err)
+	}
+
+	testRevocationStore(t, NewGormRevocationStore(db))
+
+	if !db.Migrator().HasIndex(&RevokedToken{}, "idx_jti") {
+		t.Error("HasIndex(): no unique index on jti")
+	}
+
+	store := NewGormRevocationStore(db)
+	for i := 0; i < 2; i++ {
+		if err := store.Revoke("token-1", time.Now().Add(time.Hour)); err != nil {
+			t.Fatalf("Revoke(): %v", err)
+		}
+	}
+	var count int64
+	if err := db.Model(&RevokedToken{}).Where("jti = ?", "token-1").Count(&count).Error; err != nil || count != 1 {
+		t.Errorf("Revoke(): got (%d, %v) records, want 1", count, err)
+	}
+}
+
+func TestVerifyMissingJTI(t *testing.T) {
+	token := signHS256(t, "secret-id", "secret-key", testClaims())
+
+	_, err := Verify(token, testKeys, WithRevocationStore(NewMemoryRevocationStore()))
+	if !errors.IsCode(err, ErrTokenInvalid) {
+		t.Errorf("Verify(): got %v, want code %d", err, ErrTokenInvalid)
+	}
+}
+
+func TestRunPruner(t *testing.T) {
+	store := NewMemoryRevocationStore()
+	_ = store.Revoke("expired", time.Now().Add(-time.Hour))
+	_ = store.Revoke("grace", time.Now().Add(-time.Second))
+	_ = store.Revoke("valid", time.Now().Add(time.Hour))
+
+	stopCh := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		RunPruner(store, time.Millisecond, time.Minute, stopCh)
+		close(done)
+	}()
+
+	for store.Len() != 2 {
+		time.Sleep(time.Millisecond)
+	}
+	close(stopCh)
+	<-done
+
+	if revoked, _ := store.IsRevoked("grace"); !revoked {
+		t.Error("token pruned within the grace period")
+	}
+}
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/xs0910/iam/pkg/component-base/util/idutil"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// DefaultTokenTTL is the lifetime of the tokens issued without WithTTL.
+const DefaultTokenTTL = time.Minute
+
+// registeredClaims are the claims set by NewClaims, they cannot be set by WithClaims.
+var registeredClaims = []string{"exp", "iat", "nbf", "iss", "aud", "sub", "jti"}
+
+// SignOption configures the claims of the tokens issued by Sign.
+type SignOption func(*signOptions)
+
+type signOptions struct {
+	ttl      time.Duration
+	subject  string
+	issuedAt time.Time
+	claims   map[string]interface{}
+}
+
+// WithTTL sets the lifetime of the token, which is DefaultTokenTTL by default.
+func WithTTL(ttl time.Duration) SignOption {
+	return func(o *signOptions) { o.ttl = ttl }
+}
+
+// WithSubject sets the `sub` claim of the token, e.g. the username.
+func WithSubject(sub string) SignOption {
+	return func(o *signOptions) { o.subject = sub }
+}
+
+// WithIssuedAt sets the issue time of the token, which is the current time by default.
+func WithIssuedAt(t time.Time) SignOption {
+	return func(o *signOptions) { o.issuedAt = t }
+}
+
+// WithClaims adds private claims to the token, e.g. the tenant or the scopes.
+// It can be used several times, the registered claims cannot be overridden.
+func WithClaims(claims map[string]interface{}) SignOption {
+	return func(o *signOptions) {
+		for name, value := range claims {
+			o.claims[name] = value
+		}
+	}
+}
+
+// NewClaims returns the claims of a token issued by iss for aud, with a unique `jti`.
+//
+//	claims, err := auth.NewClaims("iam-apiserver", "iam.authz.example.com",
+//		auth.WithTTL(2*time.Hour),
+//		auth.WithSubject("colin"),
+//		auth.WithClaims(map[string]interface{}{"scopes": []string{"read"}}))
+func NewClaims(iss, aud string, opts ...SignOption) (jwt.MapClaims, error) {
+	o := &signOptions{
+		ttl:      DefaultTokenTTL,
+		issuedAt: time.Now(),
+		claims:   map[string]interface{}{},
+	}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if o.ttl <= 0 {
+		return nil, errors.Errorf("invalid token ttl %s", o.ttl)
+	}
+
+	claims := jwt.MapClaims{}
+	for name, value := range o.claims {
+		claims[name] = value
+	}
+	for _, name := range registeredClaims {
+		if _, ok := claims[name]; ok {
+			return nil, errors.Errorf("registered claim %s cannot be overridden", name)
+		}
+	}
+
+	claims["exp"] = o.issuedAt.Add(o.ttl).Unix()
+	claims["iat"] = o.issuedAt.Unix()
+	claims["nbf"] = o.issuedAt.Unix()
+	claims["iss"] = iss
+	claims["aud"] = aud
+	claims["jti"] = idutil.GetUUID36("")
+	if o.subject != "" {
+		claims["sub"] = o.subject
+	}
+
+	return claims, nil
+}
+
+// Sign issue a jwt token based on secretID, secretKey, iss, aud and opts, it's signed
+// with HS256. To sign tokens with asymmetric keys, pass the claims returned by
+// NewClaims to KeySet.Sign.
+func Sign(secretID string, secretKey string, iss string, aud string, opts ...SignOption) (string, error) {
+	key, err := NewSigningKey(secretID, []byte(secretKey))
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := NewClaims(iss, aud, opts...)
+	if err != nil {
+		return "", err
+	}
+
+	return key.Sign(claims)
+}
@@ -0,0 +1,78 @@
+package auth
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/xs0910/iam/pkg/component-base/util/clock"
+)
+
+func TestSign(t *testing.T) {
+	issuedAt := time.Date(2022, 1, 29, 12, 0, 0, 0, time.UTC)
+
+	token, err := Sign("secret-id", "secret-key", "iam-apiserver", "iam.authz.example.com",
+		WithTTL(2*time.Hour),
+		WithSubject("colin"),
+		WithIssuedAt(issuedAt),
+		WithClaims(map[string]interface{}{"tenant": "acme"}),
+		WithClaims(map[string]interface{}{"scopes": []string{"read", "write"}}))
+	if err != nil {
+		t.Fatalf("Sign(): %v", err)
+	}
+
+	v := NewVerifier(testKeys,
+		WithIssuer("iam-apiserver"),
+		WithAudience("iam.authz.example.com"),
+		WithClock(clock.NewFakePassiveClock(issuedAt.Add(time.Hour))))
+
+	claims, err := v.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify(): %v", err)
+	}
+
+	if exp := claims["exp"].(json.Number).String(); exp != "1643464800" {
+		t.Errorf("got exp %s, want 1643464800", exp)
+	}
+	if claims["sub"] != "colin" || claims["tenant"] != "acme" {
+		t.Errorf("got claims %v", claims)
+	}
+	if scopes, _ := claims["scopes"].([]interface{}); len(scopes) != 2 {
+		t.Errorf("got scopes %v", claims["scopes"])
+	}
+	if jti, _ := claims["jti"].(string); jti == "" {
+		t.Error("got empty jti")
+	}
+}
+
+func TestSignUniqueJTI(t *testing.T) {
+	seen := map[interface{}]bool{}
+	for i := 0; i < 10; i++ {
+		claims, err := NewClaims("iam-apiserver", "iam.authz.example.com")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if seen[claims["jti"]] {
+			t.Fatalf("duplicate jti %v", claims["jti"])
+		}
+		seen[claims["jti"]] = true
+	}
+}
+
+func TestSignError(t *testing.T) {
+	tests := []struct {
+		name     string
+		secretID string
+		opts     []SignOption
+	}{
+		{"empty secretID", "", nil},
+		{"invalid ttl", "secret-id", []SignOption{WithTTL(0)}},
+		{"registered claim", "secret-id", []SignOption{WithClaims(map[string]interface{}{"exp": 0})}},
+	}
+
+	for _, tt := range tests {
+		if _, err := Sign(tt.secretID, "secret-key", "iam-apiserver", "iam.authz.example.com", tt.opts...); err == nil {
+			t.Errorf("%s: expected error", tt.name)
+		}
+	}
+}
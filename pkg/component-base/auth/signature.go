@@ -0,0 +1,415 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xs0910/iam/pkg/component-base/util/clock"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// SignatureAlgorithm is the scheme of the Authorization header of signed requests:
+//
+//	Authorization: IAM-HMAC-SHA256 Credential=<secretID>, SignedHeaders=host;x-iam-date;x-iam-nonce, Signature=<signature>
+//
+// The signature is the hex encoded HMAC-SHA256 of the string to sign with the secretKey:
+//
+//	IAM-HMAC-SHA256\n<X-IAM-Date>\n<X-IAM-Nonce>\n<hex(sha256(canonical request))>
+//
+// where the canonical request is:
+//
+//	<method>\n<escaped path>\n<sorted query>\n<signed headers as name:value\n>\n<signed header names>\n<hex(sha256(body))>
+const SignatureAlgorithm = "IAM-HMAC-SHA256"
+
+const (
+	// HeaderDate is the header of the time a request is signed at, in the format of SignatureTimeFormat.
+	HeaderDate = "X-IAM-Date"
+	// HeaderNonce is the header of the random nonce of a signed request.
+	HeaderNonce = "X-IAM-Nonce"
+
+	// SignatureTimeFormat is the format of the HeaderDate header.
+	SignatureTimeFormat = "20060102T150405Z"
+)
+
+// requiredSignedHeaders are the headers every signature must cover.
+var requiredSignedHeaders = []string{"host", "x-iam-date", "x-iam-nonce"}
+
+// RequestSigner signs the requests of an API client with its secretID and secretKey,
+// which are usually generated by idutil.NewSecretID and idutil.NewSecretKey.
+type RequestSigner struct {
+	SecretID  string
+	SecretKey string
+
+	// SignedHeaders are the headers signed in addition to Host, X-IAM-Date and X-IAM-Nonce,
+	// e.g. Content-Type.
+	SignedHeaders []string
+
+	// Clock is the clock of the signature time, it's the real clock if nil.
+	Clock clock.PassiveClock
+}
+
+// NewRequestSigner returns a RequestSigner signing requests with secretID and secretKey.
+func NewRequestSigner(secretID, secretKey string) *RequestSigner {
+	return &RequestSigner{SecretID: secretID, SecretKey: secretKey}
+}
+
+// Sign sets the X-IAM-Date, X-IAM-Nonce and Authorization headers of req.
+// The body of req is read and replaced to be hashed.
+func (s *RequestSigner) Sign(req *http.Request) error {
+	body, err := readBody(req, -1)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return errors.Wrap(err, "generate nonce")
+	}
+
+	req.Header.Set(HeaderDate, now(s.Clock).UTC().Format(SignatureTimeFormat))
+	req.Header.Set(HeaderNonce, hex.EncodeToString(nonce))
+
+	signedHeaders := append([]string{}, requiredSignedHeaders...)
+	for _, name := range s.SignedHeaders {
+		signedHeaders = append(signedHeaders, strings.ToLower(name))
+	}
+	signedHeaders = uniqueSorted(signedHeaders)
+
+	signature := signRequest([]byte(s.SecretKey), req, signedHeaders, body)
+	req.Header.Set("Authorization", SignatureAlgorithm+" Credential="+s.SecretID+
+		", SignedHeaders="+strings.Join(signedHeaders, ";")+", Signature="+signature)
+
+	return nil
+}
+
+// Transport returns a http.RoundTripper signing the requests sent by base,
+// http.DefaultTransport is used if base is nil.
+func (s *RequestSigner) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		// a RoundTripper must not modify the request.
+		req = req.Clone(req.Context())
+		if err := s.Sign(req); err != nil {
+			return nil, err
+		}
+
+		return base.RoundTrip(req)
+	})
+}
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) { return f(req) }
+
+// NonceStore records the nonces of signed requests to reject replayed requests.
+type NonceStore interface {
+	// Use records nonce until expiresAt, it returns false if nonce is already recorded.
+	Use(nonce string, expiresAt time.Time) (bool, error)
+}
+
+// MemoryNonceStore is a NonceStore in memory, it is safe for concurrent use.
+// The expired nonces are pruned while new nonces are recorded.
+type MemoryNonceStore struct {
+	mux    sync.Mutex
+	nonces map[string]time.Time
+	clock  clock.PassiveClock
+	next   time.Time
+}
+
+var _ NonceStore = &MemoryNonceStore{}
+
+// NewMemoryNonceStore returns an empty MemoryNonceStore, c is the real clock if nil.
+func NewMemoryNonceStore(c clock.PassiveClock) *MemoryNonceStore {
+	if c == nil {
+		c = clock.RealClock{}
+	}
+
+	return &MemoryNonceStore{nonces: map[string]time.Time{}, clock: c}
+}
+
+// Use records nonce until expiresAt, it returns false if nonce is already recorded.
+func (s *MemoryNonceStore) Use(nonce string, expiresAt time.Time) (bool, error) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+
+	now := s.clock.Now()
+	if now.After(s.next) {
+		for n, exp := range s.nonces {
+			if exp.Before(now) {
+				delete(s.nonces, n)
+			}
+		}
+		s.next = now.Add(time.Minute)
+	}
+
+	if exp, ok := s.nonces[nonce]; ok && !exp.Before(now) {
+		return false, nil
+	}
+	s.nonces[nonce] = expiresAt
+
+	return true, nil
+}
+
+// RequestVerifier verifies the requests signed by RequestSigner.
+type RequestVerifier struct {
+	// Secrets looks up the secretKey as []byte of the secretID of requests.
+	Secrets KeyStore
+
+	// MaxSkew is the maximum difference between the signature time and the current time.
+	MaxSkew time.Duration
+
+	// Nonces records the nonces of the verified requests, replay protection is disabled if nil.
+	Nonces NonceStore
+
+	// MaxBodySize is the maximum size of the bodies read to be hashed, it's unlimited if not positive.
+	MaxBodySize int64
+
+	// Clock is the clock of the current time, it's the real clock if nil.
+	Clock clock.PassiveClock
+}
+
+// NewRequestVerifier returns a RequestVerifier looking up the secretKeys in secrets, with a
+// maximum skew of 5 minutes, an in memory nonce store, and bodies up to 10 MiB.
+func NewRequestVerifier(secrets KeyStore) *RequestVerifier {
+	return &RequestVerifier{
+		Secrets:     secrets,
+		MaxSkew:     5 * time.Minute,
+		Nonces:      NewMemoryNonceStore(nil),
+		MaxBodySize: 10 << 20,
+	}
+}
+
+// Verify verifies the signature, the time and the nonce of req, and returns its secretID.
+// The body of req is read and replaced to be hashed.
+func (v *RequestVerifier) Verify(req *http.Request) (string, error) {
+	secretID, signedHeaders, signature, err := parseSignatureHeader(req.Header.Get("Authorization"))
+	if err != nil {
+		return "", err
+	}
+
+	date, err := time.Parse(SignatureTimeFormat, req.Header.Get(HeaderDate))
+	if err != nil {
+		return "", errors.WithCode(ErrInvalidAuthHeader, "invalid %s header", HeaderDate)
+	}
+	nonce := req.Header.Get(HeaderNonce)
+	if nonce == "" {
+		return "", errors.WithCode(ErrInvalidAuthHeader, "missing %s header", HeaderNonce)
+	}
+
+	t := now(v.Clock)
+	if date.Before(t.Add(-v.MaxSkew)) || date.After(t.Add(v.MaxSkew)) {
+		return "", errors.WithCode(ErrRequestExpired, "request signed at %s", date.Format(time.RFC3339))
+	}
+
+	key, err := v.Secrets.Key(secretID)
+	if err != nil {
+		return "", errors.WrapC(err, ErrSignatureInvalid, "look up secret %s", secretID)
+	}
+	secretKey, ok := key.([]byte)
+	if !ok {
+		return "", errors.WithCode(ErrSignatureInvalid, "secret %s is not a secret key", secretID)
+	}
+
+	limit := v.MaxBodySize
+	if limit <= 0 {
+		limit = -1
+	}
+	body, err := readBody(req, limit)
+	if err != nil {
+		return "", errors.WrapC(err, ErrSignatureInvalid, "read body")
+	}
+
+	expected := signRequest(secretKey, req, signedHeaders, body)
+	if !hmac.Equal([]byte(expected), []byte(signature)) {
+		return "", errors.WithCode(ErrSignatureInvalid, "signature mismatch of secret %s", secretID)
+	}
+
+	// record the nonce only for authentic requests, so forged requests cannot burn nonces.
+	if v.Nonces != nil {
+		fresh, err := v.Nonces.Use(secretID+":"+nonce, date.Add(v.MaxSkew))
+		if err != nil {
+			return "", errors.Wrap(err, "record nonce")
+		}
+		if !fresh {
+			return "", errors.WithCode(ErrNonceReused, "nonce %s of secret %s is reused", nonce, secretID)
+		}
+	}
+
+	return secretID, nil
+}
+
+// parseSignatureHeader returns the credential, the signed headers and the signature
+// of the Authorization header.
+func parseSignatureHeader(header string) (secretID string, signedHeaders []string, signature string, err error) {
+	params := strings.TrimPrefix(header, SignatureAlgorithm+" ")
+	if params == header {
+		return "", nil, "", errors.WithCode(ErrInvalidAuthHeader, "unsupported authorization scheme")
+	}
+
+	for _, param := range strings.Split(params, ",") {
+		kv := strings.SplitN(strings.TrimSpace(param), "=", 2)
+		if len(kv) != 2 {
+			return "", nil, "", errors.WithCode(ErrInvalidAuthHeader, "malformed parameter %q", param)
+		}
+
+		switch kv[0] {
+		case "Credential":
+			secretID = kv[1]
+		case "SignedHeaders":
+			signedHeaders = strings.Split(kv[1], ";")
+		case "Signature":
+			signature = kv[1]
+		}
+	}
+
+	if secretID == "" || signature == "" {
+		return "", nil, "", errors.WithCode(ErrInvalidAuthHeader, "missing credential or signature")
+	}
+
+	signed := map[string]bool{}
+	for _, name := range signedHeaders {
+		signed[name] = true
+	}
+	for _, name := range requiredSignedHeaders {
+		if !signed[name] {
+			return "", nil, "", errors.WithCode(ErrInvalidAuthHeader, "header %s must be signed", name)
+		}
+	}
+
+	return secretID, uniqueSorted(signedHeaders), signature, nil
+}
+
+// signRequest returns the signature of req.
+func signRequest(secretKey []byte, req *http.Request, signedHeaders []string, body []byte) string {
+	bodyHash := sha256.Sum256(body)
+
+	var canonical strings.Builder
+	canonical.WriteString(req.Method + "\n")
+	canonical.WriteString(canonicalPath(req.URL) + "\n")
+	canonical.WriteString(canonicalQuery(req.URL.Query()) + "\n")
+	for _, name := range signedHeaders {
+		canonical.WriteString(name + ":" + headerValue(req, name) + "\n")
+	}
+	canonical.WriteString("\n")
+	canonical.WriteString(strings.Join(signedHeaders, ";") + "\n")
+	canonical.WriteString(hex.EncodeToString(bodyHash[:]))
+
+	canonicalHash := sha256.Sum256([]byte(canonical.String()))
+	stringToSign := SignatureAlgorithm + "\n" +
+		req.Header.Get(HeaderDate) + "\n" +
+		req.Header.Get(HeaderNonce) + "\n" +
+		hex.EncodeToString(canonicalHash[:])
+
+	mac := hmac.New(sha256.New, secretKey)
+	mac.Write([]byte(stringToSign))
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// now returns the current time of c, or of the real clock if c is nil.
+func now(c clock.PassiveClock) time.Time {
+	if c == nil {
+		return time.Now()
+	}
+
+	return c.Now()
+}
+
+func canonicalPath(u *url.URL) string {
+	path := u.EscapedPath()
+	if path == "" {
+		return "/"
+	}
+
+	return path
+}
+
+// canonicalQuery returns the query sorted by key and value, and escaped with %20 for spaces.
+func canonicalQuery(query url.Values) string {
+	pairs := make([]string, 0, len(query))
+	for key, values := range query {
+		for _, value := range values {
+			pairs = append(pairs, escapeQuery(key)+"="+escapeQuery(value))
+		}
+	}
+	sort.Strings(pairs)
+
+	return strings.Join(pairs, "&")
+}
+
+func escapeQuery(s string) string {
+	return strings.ReplaceAll(url.QueryEscape(s), "+", "%20")
+}
+
+// headerValue returns the trimmed values of the header name of req joined by commas.
+func headerValue(req *http.Request, name string) string {
+	if name == "host" {
+		if req.Host != "" {
+			return req.Host
+		}
+		return req.URL.Host
+	}
+
+	// Values returns the slice of the header, the values are normalized in a copy.
+	var values []string
+	for _, value := range req.Header.Values(name) {
+		values = append(values, strings.Join(strings.Fields(value), " "))
+	}
+
+	return strings.Join(values, ",")
+}
+
+// readBody reads the body of req up to limit bytes, or without limit if limit is negative,
+// and replaces it so it can be read again.
+func readBody(req *http.Request, limit int64) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	reader := io.Reader(req.Body)
+	if limit >= 0 {
+		reader = io.LimitReader(req.Body, limit+1)
+	}
+
+	body, err := io.ReadAll(reader)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	if limit >= 0 && int64(len(body)) > limit {
+		return nil, errors.Errorf("body exceeds %d bytes", limit)
+	}
+
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	req.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+
+	return body, nil
+}
+
+func uniqueSorted(items []string) []string {
+	sort.Strings(items)
+
+	unique := items[:0]
+	for i, item := range items {
+		if i == 0 || item != items[i-1] {
+			unique = append(unique, item)
+		}
+	}
+
+	return unique
+}
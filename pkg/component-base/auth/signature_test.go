@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/xs0910/iam/pkg/component-base/util/clock"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+func newSignedRequest(t *testing.T, signer *RequestSigner, method, target, body string) *http.Request {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if err := signer.Sign(req); err != nil {
+		t.Fatalf("Sign(): %v", err)
+	}
+
+	return req
+}
+
+func TestRequestSignature(t *testing.T) {
+	now := time.Date(2022, 1, 29, 12, 0, 0, 0, time.UTC)
+	c := clock.NewFakePassiveClock(now)
+
+	signer := NewRequestSigner("secret-id", "secret-key")
+	signer.SignedHeaders = []string{"Content-Type"}
+	signer.Clock = c
+
+	tests := []struct {
+		name     string
+		mutate   func(req *http.Request)
+		wantCode int
+	}{
+		{"valid", func(req *http.Request) {}, 0},
+		{"reordered query", func(req *http.Request) { req.URL.RawQuery = "limit=10&b=2&a=1" }, 0},
+		{"tampered query", func(req *http.Request) { req.URL.RawQuery = "a=1&b=2&limit=100" }, ErrSignatureInvalid},
+		{"tampered path", func(req *http.Request) { req.URL.Path = "/v1/secrets" }, ErrSignatureInvalid},
+		{"tampered method", func(req *http.Request) { req.Method = http.MethodDelete }, ErrSignatureInvalid},
+		{"tampered body", func(req *http.Request) { req.Body = io.NopCloser(strings.NewReader(`{"name":"admin"}`)) }, ErrSignatureInvalid},
+		{"tampered header", func(req *http.Request) { req.Header.Set("Content-Type", "text/plain") }, ErrSignatureInvalid},
+		{"tampered date", func(req *http.Request) { req.Header.Set(HeaderDate, "20220129T120100Z") }, ErrSignatureInvalid},
+		{"expired", func(req *http.Request) { c.SetTime(now.Add(6 * time.Minute)) }, ErrRequestExpired},
+		{"unknown secret", func(req *http.Request) {
+			req.Header.Set("Authorization", strings.Replace(req.Header.Get("Authorization"), "secret-id", "unknown", 1))
+		}, ErrSignatureInvalid},
+		{"missing nonce", func(req *http.Request) { req.Header.Del(HeaderNonce) }, ErrInvalidAuthHeader},
+		{"unsigned host", func(req *http.Request) {
+			req.Header.Set("Authorization", strings.Replace(req.Header.Get("Authorization"), "host;", "", 1))
+		}, ErrInvalidAuthHeader},
+		{"bearer", func(req *http.Request) { req.Header.Set("Authorization", "Bearer token") }, ErrInvalidAuthHeader},
+	}
+
+	for _, tt := range tests {
+		c.SetTime(now)
+		req := newSignedRequest(t, signer, http.MethodPost, "http://iam.example.com/v1/users?b=2&a=1&limit=10", `{"name":"colin"}`)
+		tt.mutate(req)
+
+		verifier := NewRequestVerifier(testKeys)
+		verifier.Clock = c
+
+		secretID, err := verifier.Verify(req)
+		if tt.wantCode == 0 {
+			if err != nil || secretID != "secret-id" {
+				t.Errorf("%s: got (%s, %v)", tt.name, secretID, err)
+			}
+			continue
+		}
+
+		if !errors.IsCode(err, tt.wantCode) {
+			t.Errorf("%s: got error %v, want code %d", tt.name, err, tt.wantCode)
+		}
+	}
+}
+
+func TestRequestSignatureHeadersUnchanged(t *testing.T) {
+	signer := NewRequestSigner("secret-id", "secret-key")
+	signer.SignedHeaders = []string{"X-Custom"}
+
+	req := httptest.NewRequest(http.MethodGet, "http://iam.example.com/v1/users", nil)
+	req.Header.Add("X-Custom", "a   b")
+	req.Header.Add("X-Custom", " c ")
+	if err := signer.Sign(req); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewRequestVerifier(testKeys).Verify(req); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := req.Header.Values("X-Custom"); len(got) != 2 || got[0] != "a   b" || got[1] != " c " {
+		t.Errorf("got header values %q, want them unchanged", got)
+	}
+}
+
+func TestRequestSignatureReplay(t *testing.T) {
+	signer := NewRequestSigner("secret-id", "secret-key")
+	verifier := NewRequestVerifier(testKeys)
+
+	req := newSignedRequest(t, signer, http.MethodGet, "http://iam.example.com/v1/users", "")
+	if _, err := verifier.Verify(req); err != nil {
+		t.Fatalf("Verify(): %v", err)
+	}
+
+	replayed := req.Clone(req.Context())
+	if _, err := verifier.Verify(replayed); !errors.IsCode(err, ErrNonceReused) {
+		t.Errorf("Verify(): got %v, want code %d", err, ErrNonceReused)
+	}
+}
+
+func TestRequestSignatureBodyLimit(t *testing.T) {
+	signer := NewRequestSigner("secret-id", "secret-key")
+	verifier := NewRequestVerifier(testKeys)
+	verifier.MaxBodySize = 4
+
+	req := newSignedRequest(t, signer, http.MethodPost, "http://iam.example.com/v1/users", "too large")
+	if _, err := verifier.Verify(req); !errors.IsCode(err, ErrSignatureInvalid) {
+		t.Errorf("Verify(): got %v, want code %d", err, ErrSignatureInvalid)
+	}
+}
+
+func TestRequestSignerTransport(t *testing.T) {
+	verifier := NewRequestVerifier(testKeys)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, err := verifier.Verify(r); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		body, _ := io.ReadAll(r.Body)
+		_, _ = w.Write(body)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Transport: NewRequestSigner("secret-id", "secret-key").Transport(nil)}
+	resp, err := client.Post(server.URL+"/v1/users?name=colin%20lee", "application/json", strings.NewReader(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || string(body) != `{}` {
+		t.Errorf("got status %d and body %q", resp.StatusCode, body)
+	}
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	now := time.Date(2022, 1, 29, 12, 0, 0, 0, time.UTC)
+	c := clock.NewFakePassiveClock(now)
+	store := NewMemoryNonceStore(c)
+
+	if ok, _ := store.Use("nonce", now.Add(time.Minute)); !ok {
+		t.Fatal("Use(): got false for a new nonce")
+	}
+	if ok, _ := store.Use("nonce", now.Add(time.Minute)); ok {
+		t.Fatal("Use(): got true for a used nonce")
+	}
+
+	c.SetTime(now.Add(2 * time.Minute))
+	if ok, _ := store.Use("other", now.Add(3*time.Minute)); !ok {
+		t.Fatal("Use(): got false for a new nonce")
+	}
+	if len(store.nonces) != 1 {
+		t.Errorf("got %d nonces, want the expired nonce pruned", len(store.nonces))
+	}
+}
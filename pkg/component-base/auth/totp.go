@@ -0,0 +1,232 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base32"
+	"encoding/binary"
+	"fmt"
+	"hash"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/xs0910/iam/pkg/component-base/util/clock"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+const (
+	base32Alphabet       = "ABCDEFGHIJKLMNOPQRSTUVWXYZ234567"
+	recoveryCodeAlphabet = "abcdefghijklmnopqrstuvwxyz1234567890"
+)
+
+// RecoveryCodeHasher hashes the recovery codes generated by GenerateRecoveryCodes.
+var RecoveryCodeHasher = &BcryptHasher{Cost: bcrypt.DefaultCost}
+
+// GenerateTOTPSecret returns a random base32 encoded TOTP secret of 160 bits.
+func GenerateTOTPSecret() (string, error) {
+	return randomString(base32Alphabet, 32)
+}
+
+// TOTP generates and verifies the time-based one-time passwords of RFC 6238.
+type TOTP struct {
+	// Secret is the base32 encoded shared secret.
+	Secret string
+	// Digits is the number of digits of the codes.
+	Digits int
+	// Period is the duration a code is valid.
+	Period time.Duration
+	// Algorithm is the HMAC hash function: SHA1, SHA256 or SHA512.
+	Algorithm string
+	// Window is the number of periods before and after the current period whose
+	// codes are accepted, to tolerate clock drift.
+	Window int
+	// Clock is the clock of the current time, it's the real clock if nil.
+	Clock clock.PassiveClock
+}
+
+// NewTOTP returns a TOTP of secret with the settings supported by most authenticator
+// apps: 6 digits, a 30 seconds period and SHA1, accepting the codes of the previous and
+// the next period.
+func NewTOTP(secret string) *TOTP {
+	return &TOTP{
+		Secret:    secret,
+		Digits:    6,
+		Period:    30 * time.Second,
+		Algorithm: "SHA1",
+		Window:    1,
+		Clock:     clock.RealClock{},
+	}
+}
+
+// URI returns the otpauth:// provisioning URI of the TOTP for account, which is usually
+// rendered as a QR code for authenticator apps.
+func (t *TOTP) URI(issuer, account string) string {
+	params := url.Values{}
+	params.Set("secret", t.Secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", t.Algorithm)
+	params.Set("digits", strconv.Itoa(t.Digits))
+	params.Set("period", strconv.Itoa(int(t.Period/time.Second)))
+
+	label := account
+	if issuer != "" {
+		label = issuer + ":" + account
+	}
+
+	return "otpauth://totp/" + url.PathEscape(label) + "?" + params.Encode()
+}
+
+// Step returns the time step of tm.
+func (t *TOTP) Step(tm time.Time) int64 {
+	return tm.Unix() / int64(t.Period/time.Second)
+}
+
+// Generate returns the code of the time step of tm.
+func (t *TOTP) Generate(tm time.Time) (string, error) {
+	if err := t.validate(); err != nil {
+		return "", err
+	}
+
+	return t.generate(t.Step(tm))
+}
+
+// Verify reports whether code is valid within the window around the current time, and
+// returns the time step it's valid at. Codes are single-use: the step of a successful
+// verification should be stored and passed as lastStep to the next verification, so the
+// codes of this step and the previous steps are rejected. lastStep is 0 if there is none.
+func (t *TOTP) Verify(code string, lastStep int64) (int64, bool) {
+	if len(code) != t.Digits || t.validate() != nil {
+		return 0, false
+	}
+
+	current := t.Step(now(t.Clock))
+	for step := current - int64(t.Window); step <= current+int64(t.Window); step++ {
+		if step <= lastStep {
+			continue
+		}
+
+		expected, err := t.generate(step)
+		if err != nil {
+			return 0, false
+		}
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) == 1 {
+			return step, true
+		}
+	}
+
+	return 0, false
+}
+
+// validate validates the settings of the TOTP.
+func (t *TOTP) validate() error {
+	if t.Digits < 6 || t.Digits > 10 || t.Period < time.Second {
+		return errors.Errorf("invalid totp digits %d or period %s", t.Digits, t.Period)
+	}
+
+	return nil
+}
+
+// generate returns the HOTP code of RFC 4226 of counter step.
+func (t *TOTP) generate(step int64) (string, error) {
+	key, err := base32.StdEncoding.WithPadding(base32.NoPadding).DecodeString(
+		strings.ToUpper(strings.TrimRight(strings.ReplaceAll(t.Secret, " ", ""), "=")))
+	if err != nil {
+		return "", errors.Wrap(err, "decode totp secret")
+	}
+
+	var h func() hash.Hash
+	switch strings.ToUpper(t.Algorithm) {
+	case "", "SHA1":
+		h = sha1.New
+	case "SHA256":
+		h = sha256.New
+	case "SHA512":
+		h = sha512.New
+	default:
+		return "", errors.Errorf("unsupported totp algorithm %s", t.Algorithm)
+	}
+	counter := make([]byte, 8)
+	binary.BigEndian.PutUint64(counter, uint64(step))
+
+	mac := hmac.New(h, key)
+	mac.Write(counter)
+	sum := mac.Sum(nil)
+
+	// dynamic truncation.
+	offset := sum[len(sum)-1] & 0x0f
+	value := int64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff)
+
+	mod := int64(1)
+	for i := 0; i < t.Digits; i++ {
+		mod *= 10
+	}
+
+	return fmt.Sprintf("%0*d", t.Digits, value%mod), nil
+}
+
+// GenerateRecoveryCodes returns n single-use recovery codes formatted like `x4k2m-9qzt7`,
+// and their hashes by RecoveryCodeHasher, which are stored instead of the codes.
+func GenerateRecoveryCodes(n int) (codes []string, hashes []string, err error) {
+	for i := 0; i < n; i++ {
+		code, err := randomString(recoveryCodeAlphabet, 10)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		hashed, err := RecoveryCodeHasher.Hash(code)
+		if err != nil {
+			return nil, nil, errors.Wrap(err, "hash recovery code")
+		}
+
+		codes = append(codes, code[:5]+"-"+code[5:])
+		hashes = append(hashes, hashed)
+	}
+
+	return codes, hashes, nil
+}
+
+// UseRecoveryCode compares code with the hashes of the unused recovery codes, and returns
+// the hashes without the hash of code, which must be stored so code cannot be used again.
+// ok is false if code matches none of the hashes.
+func UseRecoveryCode(hashes []string, code string) (remaining []string, ok bool) {
+	code = strings.ToLower(strings.NewReplacer("-", "", " ", "").Replace(code))
+
+	for i, hashed := range hashes {
+		if RecoveryCodeHasher.Compare(hashed, code) == nil {
+			remaining = append(append(remaining, hashes[:i]...), hashes[i+1:]...)
+			return remaining, true
+		}
+	}
+
+	return hashes, false
+}
+
+// randomString returns n random characters of letters like idutil.randString, the random
+// bytes which would bias the modulo are discarded.
+func randomString(letters string, n int) (string, error) {
+	limit := 256 - 256%len(letters)
+
+	output := make([]byte, 0, n)
+	randomness := make([]byte, n)
+	for len(output) < n {
+		if _, err := rand.Read(randomness); err != nil {
+			return "", errors.Wrap(err, "read random bytes")
+		}
+
+		for _, random := range randomness {
+			if int(random) < limit && len(output) < n {
+				output = append(output, letters[int(random)%len(letters)])
+			}
+		}
+	}
+
+	return string(output), nil
+}
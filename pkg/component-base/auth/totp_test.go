@@ -0,0 +1,132 @@
+package auth
+
+import (
+	"encoding/base32"
+	"strings"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/xs0910/iam/pkg/component-base/util/clock"
+)
+
+// TestTOTPGenerate checks the test vectors of RFC 6238 appendix B.
+func TestTOTPGenerate(t *testing.T) {
+	secrets := map[string]string{
+		"SHA1":   "12345678901234567890",
+		"SHA256": "12345678901234567890123456789012",
+		"SHA512": "1234567890123456789012345678901234567890123456789012345678901234",
+	}
+
+	tests := []struct {
+		unix      int64
+		algorithm string
+		want      string
+	}{
+		{59, "SHA1", "94287082"},
+		{59, "SHA256", "46119246"},
+		{59, "SHA512", "90693936"},
+		{1111111109, "SHA1", "07081804"},
+		{1111111111, "SHA256", "67062674"},
+		{1234567890, "SHA512", "93441116"},
+		{2000000000, "SHA1", "69279037"},
+		{20000000000, "SHA256", "77737706"},
+	}
+
+	for _, tt := range tests {
+		totp := NewTOTP(base32.StdEncoding.EncodeToString([]byte(secrets[tt.algorithm])))
+		totp.Digits = 8
+		totp.Algorithm = tt.algorithm
+
+		got, err := totp.Generate(time.Unix(tt.unix, 0))
+		if err != nil || got != tt.want {
+			t.Errorf("Generate(%d, %s): got (%s, %v), want %s", tt.unix, tt.algorithm, got, err, tt.want)
+		}
+	}
+}
+
+func TestTOTPVerify(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2022, 1, 29, 12, 0, 0, 0, time.UTC)
+	c := clock.NewFakePassiveClock(now)
+	totp := NewTOTP(secret)
+	totp.Clock = c
+
+	code, _ := totp.Generate(now)
+	step, ok := totp.Verify(code, 0)
+	if !ok || step != totp.Step(now) {
+		t.Fatalf("Verify(): got (%d, %v)", step, ok)
+	}
+	if _, ok := totp.Verify(code, step); ok {
+		t.Error("Verify(): reused code accepted")
+	}
+
+	previous, _ := totp.Generate(now.Add(-30 * time.Second))
+	if _, ok := totp.Verify(previous, 0); !ok {
+		t.Error("Verify(): code of the previous period rejected")
+	}
+
+	c.SetTime(now.Add(2 * time.Minute))
+	if _, ok := totp.Verify(code, 0); ok {
+		t.Error("Verify(): code outside the window accepted")
+	}
+
+	if _, ok := totp.Verify("12345", 0); ok {
+		t.Error("Verify(): code of the wrong length accepted")
+	}
+
+	// the real clock is used without Clock.
+	literal := &TOTP{Secret: secret, Digits: 6, Period: 30 * time.Second, Window: 1}
+	code, _ = literal.Generate(time.Now())
+	if _, ok := literal.Verify(code, 0); !ok {
+		t.Error("Verify(): code of the real clock rejected without Clock")
+	}
+}
+
+func TestGenerateTOTPSecret(t *testing.T) {
+	secret, err := GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	key, err := base32.StdEncoding.DecodeString(secret)
+	if err != nil || len(key) != 20 {
+		t.Errorf("got secret %s of %d bytes: %v", secret, len(key), err)
+	}
+}
+
+func TestTOTPURI(t *testing.T) {
+	totp := NewTOTP("JBSWY3DPEHPK3PXP")
+
+	want := "otpauth://totp/IAM:colin@example.com?algorithm=SHA1&digits=6&issuer=IAM&period=30&secret=JBSWY3DPEHPK3PXP"
+	if got := totp.URI("IAM", "colin@example.com"); got != want {
+		t.Errorf("URI(): got %s, want %s", got, want)
+	}
+}
+
+func TestRecoveryCodes(t *testing.T) {
+	defer func(cost int) { RecoveryCodeHasher.Cost = cost }(RecoveryCodeHasher.Cost)
+	RecoveryCodeHasher.Cost = bcrypt.MinCost
+
+	codes, hashes, err := GenerateRecoveryCodes(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(codes) != 3 || len(hashes) != 3 || len(codes[0]) != 11 || codes[0][5] != '-' {
+		t.Fatalf("got codes %v", codes)
+	}
+
+	remaining, ok := UseRecoveryCode(hashes, strings.ToUpper(codes[1]))
+	if !ok || len(remaining) != 2 || remaining[0] != hashes[0] || remaining[1] != hashes[2] {
+		t.Fatalf("UseRecoveryCode(): got (%v, %v)", remaining, ok)
+	}
+
+	if _, ok := UseRecoveryCode(remaining, codes[1]); ok {
+		t.Error("UseRecoveryCode(): used code accepted")
+	}
+}
@@ -0,0 +1,295 @@
+package auth
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"encoding/json"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/xs0910/iam/pkg/component-base/util/clock"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// KeyStore looks up the keys used to verify tokens by the key ID in the `kid` header.
+type KeyStore interface {
+	// Key returns the verification key of kid, which is the secret key as []byte for HMAC tokens,
+	// or an *rsa.PublicKey, *ecdsa.PublicKey or ed25519.PublicKey. It returns an error if kid is unknown.
+	Key(kid string) (interface{}, error)
+}
+
+// KeyStoreFunc is an adapter to allow the use of ordinary functions as KeyStore.
+type KeyStoreFunc func(kid string) (interface{}, error)
+
+// Key calls f(kid).
+func (f KeyStoreFunc) Key(kid string) (interface{}, error) {
+	return f(kid)
+}
+
+// SecretKeyStore is a KeyStore of HMAC secret keys, keyed by secretID.
+type SecretKeyStore map[string]string
+
+// Key returns the secret key of the secretID kid.
+func (s SecretKeyStore) Key(kid string) (interface{}, error) {
+	key, ok := s[kid]
+	if !ok {
+		return nil, errors.Errorf("secret %s not found", kid)
+	}
+
+	return []byte(key), nil
+}
+
+// VerifyOption configures a Verifier.
+type VerifyOption func(*Verifier)
+
+// WithIssuer requires the `iss` claim of tokens to be iss.
+func WithIssuer(iss string) VerifyOption {
+	return func(v *Verifier) { v.issuer = iss }
+}
+
+// WithAudience requires the `aud` claim of tokens to contain aud.
+func WithAudience(aud string) VerifyOption {
+	return func(v *Verifier) { v.audience = aud }
+}
+
+// WithLeeway sets the clock skew tolerated when validating the `exp`, `nbf` and `iat` claims.
+func WithLeeway(leeway time.Duration) VerifyOption {
+	return func(v *Verifier) { v.leeway = leeway }
+}
+
+// WithClock sets the clock used to validate the `exp`, `nbf` and `iat` claims.
+func WithClock(c clock.PassiveClock) VerifyOption {
+	return func(v *Verifier) { v.clock = c }
+}
+
+// AllowMissingExpiration accepts the tokens without an `exp` claim, which never expire.
+// They are rejected by default.
+func AllowMissingExpiration() VerifyOption {
+	return func(v *Verifier) { v.allowMissingExp = true }
+}
+
+// Verifier verifies the signature and the claims of tokens.
+type Verifier struct {
+	keys     KeyStore
+	issuer   string
+	audience string
+	leeway   time.Duration
+	clock    clock.PassiveClock
+
+	allowMissingExp bool
+
+	revocations RevocationStore
+}
+
+// NewVerifier returns a Verifier which looks up the verification keys in keys.
+func NewVerifier(keys KeyStore, opts ...VerifyOption) *Verifier {
+	v := &Verifier{
+		keys:  keys,
+		clock: clock.RealClock{},
+	}
+	for _, opt := range opts {
+		opt(v)
+	}
+
+	return v
+}
+
+// Verify verifies tokenString with a Verifier built from keys and opts, and returns its claims.
+func Verify(tokenString string, keys KeyStore, opts ...VerifyOption) (jwt.MapClaims, error) {
+	return NewVerifier(keys, opts...).Verify(tokenString)
+}
+
+// Parse parses tokenString without verifying it, it returns the claims and the key ID of the token.
+// Never trust the claims returned by Parse, use Verify instead.
+func Parse(tokenString string) (jwt.MapClaims, string, error) {
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{UseJSONNumber: true}
+
+	token, _, err := parser.ParseUnverified(tokenString, claims)
+	if err != nil {
+		return nil, "", errors.WrapC(err, ErrTokenInvalid, "malformed token")
+	}
+
+	kid, _ := token.Header["kid"].(string)
+	return claims, kid, nil
+}
+
+// Verify verifies the signature of tokenString with the key of its `kid` header, then
+// validates its `exp`, `nbf`, `iat`, `iss` and `aud` claims and checks it's not revoked,
+// and returns the claims.
+func (v *Verifier) Verify(tokenString string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	parser := &jwt.Parser{UseJSONNumber: true, SkipClaimsValidation: true}
+
+	_, err := parser.ParseWithClaims(tokenString, claims, v.keyFunc)
+	if err != nil {
+		return nil, parseError(err)
+	}
+
+	if err := v.validate(claims); err != nil {
+		return nil, err
+	}
+
+	if v.revocations != nil {
+		if err := v.checkRevoked(claims); err != nil {
+			return nil, err
+		}
+	}
+
+	return claims, nil
+}
+
+// keyFunc looks up the key of the token and checks it is used with a matching signing method.
+func (v *Verifier) keyFunc(token *jwt.Token) (interface{}, error) {
+	kid, ok := token.Header["kid"].(string)
+	if !ok || kid == "" {
+		return nil, errors.WithCode(ErrUnknownKey, "missing kid header")
+	}
+
+	key, err := v.keys.Key(kid)
+	if err != nil {
+		return nil, errors.WrapC(err, ErrUnknownKey, "look up key %s", kid)
+	}
+
+	if err := checkSigningMethod(token.Method, key); err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
+// checkSigningMethod prevents tokens from being verified with a key of another algorithm.
+func checkSigningMethod(method jwt.SigningMethod, key interface{}) error {
+	var ok bool
+	switch key.(type) {
+	case []byte:
+		_, ok = method.(*jwt.SigningMethodHMAC)
+	case *rsa.PublicKey:
+		switch method.(type) {
+		case *jwt.SigningMethodRSA, *jwt.SigningMethodRSAPSS:
+			ok = true
+		}
+	case *ecdsa.PublicKey:
+		_, ok = method.(*jwt.SigningMethodECDSA)
+	case ed25519.PublicKey:
+		_, ok = method.(*SigningMethodEdDSA)
+	}
+	if ok {
+		return nil
+	}
+
+	return errors.WithCode(ErrSignatureInvalid, "unexpected signing method %s", method.Alg())
+}
+
+// parseError converts the errors returned by jwt.Parser into withCode errors.
+func parseError(err error) error {
+	verr, ok := err.(*jwt.ValidationError)
+	if !ok {
+		return errors.WrapC(err, ErrTokenInvalid, "invalid token")
+	}
+
+	switch {
+	case verr.Errors&jwt.ValidationErrorUnverifiable != 0 && verr.Inner != nil:
+		// the withCode error returned by keyFunc
+		return verr.Inner
+	case verr.Errors&jwt.ValidationErrorMalformed != 0:
+		return errors.WrapC(err, ErrTokenInvalid, "malformed token")
+	case verr.Errors&jwt.ValidationErrorSignatureInvalid != 0:
+		return errors.WrapC(err, ErrSignatureInvalid, "signature is invalid")
+	}
+
+	return errors.WrapC(err, ErrTokenInvalid, "invalid token")
+}
+
+// validate validates the time based claims with leeway, and the issuer and audience claims.
+// The `exp` claim is required unless allowed to be missing by AllowMissingExpiration.
+func (v *Verifier) validate(claims jwt.MapClaims) error {
+	now := v.clock.Now()
+
+	exp, ok, err := numericDate(claims, "exp")
+	if err != nil {
+		return err
+	}
+	if !ok && !v.allowMissingExp {
+		return errors.WithCode(ErrTokenInvalid, "missing exp claim")
+	}
+	if ok && !now.Before(exp.Add(v.leeway)) {
+		return errors.WithCode(ErrExpired, "token expired at %s", exp.Format(time.RFC3339))
+	}
+
+	nbf, ok, err := numericDate(claims, "nbf")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(v.leeway).Before(nbf) {
+		return errors.WithCode(ErrTokenNotValidYet, "token is not valid before %s", nbf.Format(time.RFC3339))
+	}
+
+	iat, ok, err := numericDate(claims, "iat")
+	if err != nil {
+		return err
+	}
+	if ok && now.Add(v.leeway).Before(iat) {
+		return errors.WithCode(ErrTokenNotValidYet, "token is issued in the future at %s", iat.Format(time.RFC3339))
+	}
+
+	if v.issuer != "" {
+		if iss, _ := claims["iss"].(string); iss != v.issuer {
+			return errors.WithCode(ErrInvalidIssuer, "unexpected issuer %q", iss)
+		}
+	}
+
+	if v.audience != "" && !hasAudience(claims["aud"], v.audience) {
+		return errors.WithCode(ErrInvalidAudience, "audience %q not found", v.audience)
+	}
+
+	return nil
+}
+
+// numericDate returns the time of the NumericDate claim name, ok is false if the claim is absent.
+func numericDate(claims jwt.MapClaims, name string) (t time.Time, ok bool, err error) {
+	value, ok := claims[name]
+	if !ok {
+		return time.Time{}, false, nil
+	}
+
+	var seconds float64
+	switch v := value.(type) {
+	case json.Number:
+		seconds, err = v.Float64()
+	case float64:
+		seconds = v
+	default:
+		err = errors.Errorf("unexpected type %T", value)
+	}
+	if err != nil {
+		return time.Time{}, false, errors.WrapC(err, ErrTokenInvalid, "invalid %s claim", name)
+	}
+
+	sec := int64(seconds)
+	return time.Unix(sec, int64((seconds-float64(sec))*float64(time.Second))), true, nil
+}
+
+// hasAudience reports whether the `aud` claim, a string or an array of strings, contains aud.
+func hasAudience(claim interface{}, aud string) bool {
+	switch v := claim.(type) {
+	case string:
+		return v == aud
+	case []interface{}:
+		for _, a := range v {
+			if s, ok := a.(string); ok && s == aud {
+				return true
+			}
+		}
+	case []string:
+		for _, s := range v {
+			if s == aud {
+				return true
+			}
+		}
+	}
+
+	return false
+}
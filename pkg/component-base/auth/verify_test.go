@@ -0,0 +1,128 @@
+package auth
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+
+	"github.com/xs0910/iam/pkg/component-base/util/clock"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+var testKeys = SecretKeyStore{"secret-id": "secret-key"}
+
+func signHS256(t *testing.T, kid, key string, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+
+	s, err := token.SignedString([]byte(key))
+	if err != nil {
+		t.Fatalf("SignedString(): %v", err)
+	}
+
+	return s
+}
+
+func TestVerify(t *testing.T) {
+	now := time.Date(2022, 1, 29, 12, 0, 0, 0, time.UTC)
+	c := clock.NewFakePassiveClock(now)
+
+	valid := func() jwt.MapClaims {
+		return jwt.MapClaims{
+			"exp": now.Add(time.Minute).Unix(),
+			"iat": now.Unix(),
+			"nbf": now.Unix(),
+			"iss": "iam-apiserver",
+			"aud": "iam.authz.example.com",
+		}
+	}
+	with := func(key string, value interface{}) jwt.MapClaims {
+		claims := valid()
+		claims[key] = value
+		return claims
+	}
+	without := func(key string) jwt.MapClaims {
+		claims := valid()
+		delete(claims, key)
+		return claims
+	}
+
+	tests := []struct {
+		name     string
+		token    string
+		wantCode int
+	}{
+		{"valid", signHS256(t, "secret-id", "secret-key", valid()), 0},
+		{"audience array", signHS256(t, "secret-id", "secret-key", with("aud", []string{"a", "iam.authz.example.com"})), 0},
+		{"expired within leeway", signHS256(t, "secret-id", "secret-key", with("exp", now.Add(-5*time.Second).Unix())), 0},
+		{"expired", signHS256(t, "secret-id", "secret-key", with("exp", now.Add(-time.Minute).Unix())), ErrExpired},
+		{"not valid yet", signHS256(t, "secret-id", "secret-key", with("nbf", now.Add(time.Minute).Unix())), ErrTokenNotValidYet},
+		{"issued in future", signHS256(t, "secret-id", "secret-key", with("iat", now.Add(time.Minute).Unix())), ErrTokenNotValidYet},
+		{"invalid exp", signHS256(t, "secret-id", "secret-key", with("exp", "tomorrow")), ErrTokenInvalid},
+		{"missing exp", signHS256(t, "secret-id", "secret-key", without("exp")), ErrTokenInvalid},
+		{"wrong issuer", signHS256(t, "secret-id", "secret-key", with("iss", "evil")), ErrInvalidIssuer},
+		{"wrong audience", signHS256(t, "secret-id", "secret-key", with("aud", "other")), ErrInvalidAudience},
+		{"bad signature", signHS256(t, "secret-id", "other-key", valid()), ErrSignatureInvalid},
+		{"unknown kid", signHS256(t, "unknown", "secret-key", valid()), ErrUnknownKey},
+		{"missing kid", signHS256(t, "", "secret-key", valid()), ErrUnknownKey},
+		{"malformed", "not.a.token", ErrTokenInvalid},
+		{"none algorithm", noneToken(t, valid()), ErrSignatureInvalid},
+	}
+
+	v := NewVerifier(testKeys,
+		WithIssuer("iam-apiserver"),
+		WithAudience("iam.authz.example.com"),
+		WithLeeway(10*time.Second),
+		WithClock(c))
+
+	for _, tt := range tests {
+		claims, err := v.Verify(tt.token)
+		if tt.wantCode == 0 {
+			if err != nil {
+				t.Errorf("%s: unexpected error %v", tt.name, err)
+			} else if claims["iss"] != "iam-apiserver" {
+				t.Errorf("%s: got claims %v", tt.name, claims)
+			}
+			continue
+		}
+
+		if !errors.IsCode(err, tt.wantCode) {
+			t.Errorf("%s: got error %#-v, want code %d", tt.name, err, tt.wantCode)
+		}
+	}
+
+	// the tokens without exp are accepted when allowed explicitly.
+	token := signHS256(t, "secret-id", "secret-key", without("exp"))
+	if _, err := Verify(token, testKeys, WithClock(c), AllowMissingExpiration()); err != nil {
+		t.Errorf("AllowMissingExpiration(): unexpected error %v", err)
+	}
+}
+
+func noneToken(t *testing.T, claims jwt.MapClaims) string {
+	token := jwt.NewWithClaims(jwt.SigningMethodNone, claims)
+	token.Header["kid"] = "secret-id"
+
+	s, err := token.SignedString(jwt.UnsafeAllowNoneSignatureType)
+	if err != nil {
+		t.Fatalf("SignedString(): %v", err)
+	}
+
+	return s
+}
+
+func TestParse(t *testing.T) {
+	token := signHS256(t, "secret-id", "whatever", jwt.MapClaims{"iss": "iam-apiserver"})
+
+	claims, kid, err := Parse(token)
+	if err != nil || kid != "secret-id" || claims["iss"] != "iam-apiserver" {
+		t.Errorf("Parse(): got (%v, %q, %v)", claims, kid, err)
+	}
+
+	if _, _, err := Parse(strings.Repeat("x", 10)); !errors.IsCode(err, ErrTokenInvalid) {
+		t.Errorf("Parse(): got error %v, want code %d", err, ErrTokenInvalid)
+	}
+}
@@ -2,38 +2,213 @@ package core
 
 import (
 	"github.com/gin-gonic/gin"
+	metav1 "github.com/xs0910/iam/pkg/component-base/meta/v1"
+	"github.com/xs0910/iam/pkg/component-base/runtime"
 	"github.com/xs0910/iam/pkg/errors"
-	"log"
 	"net/http"
+	"strconv"
 )
 
+// XTotalCountKey defines the header which carries the total count of list responses.
+const XTotalCountKey = "X-Total-Count"
+
 // Response defines the return messages when an error occurred.
 // Reference will be omitted if it does not exist.
 type Response struct {
-	Code      int         `json:"code"`                // Code define the business error code.
-	Message   string      `json:"message"`             // Message contains the detail of this message.
-	Data      interface{} `json:"data"`                // Data define the business data
-	Reference string      `json:"reference,omitempty"` // Reference returns the reference document which maybe useful to solve this error.
+	Code      int           `json:"code"`                // Code define the business error code.
+	Message   string        `json:"message"`             // Message contains the detail of this message.
+	Data      interface{}   `json:"data"`                // Data define the business data
+	Reference string        `json:"reference,omitempty"` // Reference returns the reference document which maybe useful to solve this error.
+	Details   []FieldDetail `json:"details,omitempty"`   // Details lists the invalid fields when the validation of the request failed.
 }
 
-func WriteResponse(c *gin.Context, err error, data interface{}) {
-	if err != nil {
-		// TODO log需要统一一下
-		log.Printf("%#+v", err)
+// compactResponse defines the return messages of the compact media type when an error occurred.
+// The business data is written without any envelope on success.
+type compactResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// ResponseOption customizes the success response written by WriteResponse.
+type ResponseOption func(*responseOptions)
+
+type responseOptions struct {
+	httpStatus int
+	code       int
+	message    string
+	fields     []string
+}
+
+// WithHTTPStatus sets the HTTP status of the success response, defaults to 200.
+func WithHTTPStatus(status int) ResponseOption {
+	return func(o *responseOptions) { o.httpStatus = status }
+}
 
+// WithSuccessCode sets the business code of the success response, defaults to 200.
+func WithSuccessCode(code int) ResponseOption {
+	return func(o *responseOptions) { o.code = code }
+}
+
+// WithSuccessMessage sets the message of the success response, defaults to "success".
+func WithSuccessMessage(message string) ResponseOption {
+	return func(o *responseOptions) { o.message = message }
+}
+
+// WithFields omits the fields of the objects in the data of the success response which
+// are not in fields, see runtime.NewFieldsEncoder. All the fields are written if empty.
+func WithFields(fields []string) ResponseOption {
+	return func(o *responseOptions) { o.fields = fields }
+}
+
+// WriteResponse write an error or the response data into http response body.
+// The external error message is localized according to the Accept-Language header
+// of the request, see errors.RegisterMessages. The error is logged by the Logger
+// set with SetLogger.
+// The response is encoded in the media type negotiated from the Accept header among
+// runtime.SupportedMediaTypes, JSON is used when none is acceptable.
+// When data is a list which embeds metav1.ListMeta, the pagination headers are written too.
+// Errors are rendered as RFC 7807 problem details when application/problem+json is
+// accepted or the route uses the ProblemDetails middleware.
+func WriteResponse(c *gin.Context, err error, data interface{}, opts ...ResponseOption) {
+	if err != nil {
 		coder := errors.ParseCoder(err)
-		c.JSON(coder.HTTPStatus(), Response{
+		getLogger().LogError(newLogEntry(c, err, coder))
+
+		mediaType := negotiateMediaType(c, errorMediaTypes...)
+		if wantsProblem(c, mediaType) {
+			write(c, coder.HTTPStatus(), ContentTypeProblemJSON, NewProblem(c, err))
+			return
+		}
+
+		message := errors.LocalizedString(coder, c.GetHeader("Accept-Language"))
+		if mediaType == runtime.ContentTypeCompactJSON {
+			write(c, coder.HTTPStatus(), mediaType, compactResponse{Code: coder.Code(), Message: message})
+			return
+		}
+
+		write(c, coder.HTTPStatus(), mediaType, Response{
 			Code:      coder.Code(),
-			Message:   coder.String(),
+			Message:   message,
 			Reference: coder.Reference(),
 			Data:      nil,
+			Details:   fieldDetails(err),
 		})
 		return
 	}
 
-	c.JSON(http.StatusOK, Response{
-		Code:    200,
-		Message: "success",
+	mediaType := negotiateMediaType(c, runtime.SupportedMediaTypes...)
+
+	o := &responseOptions{httpStatus: http.StatusOK, code: 200, message: "success"}
+	for _, opt := range opts {
+		opt(o)
+	}
+
+	if list, ok := data.(metav1.ListMetaAccessor); ok {
+		writePaginationHeaders(c, list.GetListMeta())
+	}
+
+	data, err = runtime.SelectFields(data, o.fields)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	if mediaType == runtime.ContentTypeCompactJSON {
+		write(c, o.httpStatus, mediaType, data)
+		return
+	}
+
+	write(c, o.httpStatus, mediaType, Response{
+		Code:    o.code,
+		Message: o.message,
 		Data:    data,
 	})
 }
+
+// errorMediaTypes lists the media types of error responses in order of preference.
+var errorMediaTypes = append(append([]string{}, runtime.SupportedMediaTypes...), ContentTypeProblemJSON)
+
+// negotiateMediaType returns the media type of the response among supported according
+// to the Accept header.
+func negotiateMediaType(c *gin.Context, supported ...string) string {
+	mediaType, ok := runtime.NegotiateMediaType(c.GetHeader("Accept"), supported)
+	if !ok {
+		return runtime.ContentTypeJSON
+	}
+
+	return mediaType
+}
+
+// write encodes obj in the media type and writes it with the HTTP status.
+func write(c *gin.Context, status int, mediaType string, obj interface{}) {
+	negotiator := runtime.NewClientNegotiator(mediaType)
+	if mediaType == ContentTypeProblemJSON {
+		negotiator = runtime.NewClientNegotiator(runtime.ContentTypeJSON)
+	}
+
+	encoder, err := negotiator.Encoder()
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	body, err := encoder.Encode(obj)
+	if err != nil {
+		_ = c.AbortWithError(http.StatusInternalServerError, err)
+		return
+	}
+
+	c.Data(status, mediaType+"; charset=utf-8", body)
+}
+
+// writePaginationHeaders writes the total count of the list, and the links to the
+// next and previous pages when the request is paginated by the offset and limit query,
+// or the link to the next page when the list has a continue token.
+func writePaginationHeaders(c *gin.Context, list metav1.ListInterface) {
+	total := list.GetTotalCount()
+	c.Header(XTotalCountKey, strconv.FormatInt(total, 10))
+
+	if token := list.GetContinue(); token != "" {
+		u := *c.Request.URL
+		query := u.Query()
+		query.Del("offset")
+		query.Set("continue", token)
+		u.RawQuery = query.Encode()
+
+		c.Writer.Header().Add("Link", "<"+u.RequestURI()+`>; rel="next"`)
+		return
+	}
+
+	offset, _ := strconv.ParseInt(c.Query("offset"), 10, 64)
+	limit, err := strconv.ParseInt(c.Query("limit"), 10, 64)
+	if err != nil || limit <= 0 || offset < 0 {
+		return
+	}
+
+	var links []string
+	if offset+limit < total {
+		links = append(links, pageLink(c, offset+limit, limit, "next"))
+	}
+	if offset > 0 {
+		prev := offset - limit
+		if prev < 0 {
+			prev = 0
+		}
+		links = append(links, pageLink(c, prev, limit, "prev"))
+	}
+
+	for _, link := range links {
+		c.Writer.Header().Add("Link", link)
+	}
+}
+
+// pageLink returns the Link header value of the page starting at offset.
+func pageLink(c *gin.Context, offset, limit int64, rel string) string {
+	u := *c.Request.URL
+	query := u.Query()
+	query.Set("offset", strconv.FormatInt(offset, 10))
+	query.Set("limit", strconv.FormatInt(limit, 10))
+	u.RawQuery = query.Encode()
+
+	return "<" + u.RequestURI() + `>; rel="` + rel + `"`
+}
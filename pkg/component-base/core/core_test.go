@@ -0,0 +1,243 @@
+package core
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	metav1 "github.com/xs0910/iam/pkg/component-base/meta/v1"
+	"github.com/xs0910/iam/pkg/component-base/runtime"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+const (
+	errCoreNotFound int = iota + 990001
+	errCoreInternal
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+
+	errors.Register(errors.NewCoder(errCoreNotFound, http.StatusNotFound, "Not found", ""))
+	errors.Register(errors.NewCoder(errCoreInternal, http.StatusInternalServerError, "Internal error", ""))
+}
+
+type fakeLogger struct {
+	entries []*LogEntry
+}
+
+func (l *fakeLogger) LogError(entry *LogEntry) {
+	l.entries = append(l.entries, entry)
+}
+
+// serve runs handler on the route /users/:name and returns the recorder.
+func serve(handler gin.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.GET("/users/:name", handler)
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestWriteResponseLogging(t *testing.T) {
+	l := &fakeLogger{}
+	SetLogger(l)
+	defer SetLogger(nil)
+
+	tests := []struct {
+		err          error
+		wantStatus   int
+		wantSeverity Severity
+		wantStack    bool
+	}{
+		{errors.WithCode(errCoreNotFound, "user foo not found"), http.StatusNotFound, SeverityWarning, false},
+		{errors.WrapC(errors.New("db down"), errCoreInternal, "get user"), http.StatusInternalServerError, SeverityError, true},
+	}
+
+	for i, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/users/foo", nil)
+		req.Header.Set(XRequestIDKey, "rid-1")
+		req.Header.Set("X-Real-IP", "10.0.0.1")
+
+		w := serve(func(c *gin.Context) { WriteResponse(c, tt.err, nil) }, req)
+		if w.Code != tt.wantStatus {
+			t.Errorf("WriteResponse(%d): got status %d, want: %d", i, w.Code, tt.wantStatus)
+		}
+
+		entry := l.entries[len(l.entries)-1]
+		if entry.RequestID != "rid-1" || entry.Route != "/users/:name" || entry.ClientIP != "10.0.0.1" ||
+			entry.Method != http.MethodGet || entry.HTTPStatus != tt.wantStatus || entry.Severity != tt.wantSeverity {
+			t.Errorf("WriteResponse(%d): unexpected log entry %+v", i, entry)
+		}
+
+		// the %#+v form logs every error of the chain, the %#-v form only the outermost one
+		if got := strings.Count(entry.Error, `"caller"`) > 1; got != tt.wantStack {
+			t.Errorf("WriteResponse(%d): stack logged: %v, want: %v, error: %s", i, got, tt.wantStack, entry.Error)
+		}
+	}
+}
+
+func TestSeverityForStatus(t *testing.T) {
+	tests := map[int]Severity{
+		http.StatusOK:                  SeverityInfo,
+		http.StatusBadRequest:          SeverityWarning,
+		http.StatusNotFound:            SeverityWarning,
+		http.StatusInternalServerError: SeverityError,
+		http.StatusBadGateway:          SeverityError,
+	}
+
+	for status, want := range tests {
+		if got := SeverityForStatus(status); got != want {
+			t.Errorf("SeverityForStatus(%d): got %q, want: %q", status, got, want)
+		}
+	}
+}
+
+type userList struct {
+	metav1.ListMeta `json:",inline"`
+
+	Items []string `json:"items"`
+}
+
+func TestWriteResponseNegotiation(t *testing.T) {
+	tests := []struct {
+		accept      string
+		err         error
+		wantType    string
+		wantBody    string
+		wantStatus  int
+		writeOption []ResponseOption
+	}{
+		{"", nil, "application/json", `{"code":200,"message":"success","data":"foo"}`, http.StatusOK, nil},
+		{"text/html, */*;q=0.1", nil, "application/json", `{"code":200,"message":"success","data":"foo"}`, http.StatusOK, nil},
+		{"application/yaml", nil, "application/yaml", "code: 200\ndata: foo\nmessage: success\n", http.StatusOK, nil},
+		{"application/vnd.iam.compact+json", nil, "application/vnd.iam.compact+json", `"foo"`, http.StatusOK, nil},
+		{"text/html", nil, "application/json", `{"code":201,"message":"created","data":"foo"}`, http.StatusCreated,
+			[]ResponseOption{WithHTTPStatus(http.StatusCreated), WithSuccessCode(201), WithSuccessMessage("created")}},
+		{"application/vnd.iam.compact+json", errors.WithCode(errCoreNotFound, "not found"), "application/vnd.iam.compact+json",
+			`{"code":990001,"message":"Not found"}`, http.StatusNotFound, nil},
+		{"application/yaml;q=0.9, application/json;q=0.5", errors.WithCode(errCoreNotFound, "not found"), "application/yaml",
+			"code: 990001\ndata: null\nmessage: Not found\n", http.StatusNotFound, nil},
+	}
+
+	SetLogger(&fakeLogger{})
+	defer SetLogger(nil)
+
+	for i, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/users/foo", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+
+		w := serve(func(c *gin.Context) { WriteResponse(c, tt.err, "foo", tt.writeOption...) }, req)
+		if w.Code != tt.wantStatus {
+			t.Errorf("WriteResponse(%d): got status %d, want: %d", i, w.Code, tt.wantStatus)
+		}
+		if got := w.Header().Get("Content-Type"); !strings.HasPrefix(got, tt.wantType) {
+			t.Errorf("WriteResponse(%d): got content type %q, want: %q", i, got, tt.wantType)
+		}
+		if got := w.Body.String(); got != tt.wantBody {
+			t.Errorf("WriteResponse(%d): got body %q, want: %q", i, got, tt.wantBody)
+		}
+	}
+}
+
+func TestWriteResponsePagination(t *testing.T) {
+	list := &userList{ListMeta: metav1.ListMeta{TotalCount: 25}, Items: []string{"a", "b"}}
+
+	req := httptest.NewRequest(http.MethodGet, "/users/foo?offset=10&limit=10", nil)
+	w := serve(func(c *gin.Context) { WriteResponse(c, nil, list) }, req)
+
+	if got := w.Header().Get(XTotalCountKey); got != "25" {
+		t.Errorf("WriteResponse(): got %s %q, want: %q", XTotalCountKey, got, "25")
+	}
+
+	want := []string{
+		`</users/foo?limit=10&offset=20>; rel="next"`,
+		`</users/foo?limit=10&offset=0>; rel="prev"`,
+	}
+	if got := w.Header().Values("Link"); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Errorf("WriteResponse(): got links %q, want: %q", got, want)
+	}
+
+	if got, want := w.Body.String(), `{"code":200,"message":"success","data":{"totalCount":25,"items":["a","b"]}}`; got != want {
+		t.Errorf("WriteResponse(): got body %q, want: %q", got, want)
+	}
+}
+
+func TestWriteResponseContinue(t *testing.T) {
+	list := &userList{ListMeta: metav1.ListMeta{TotalCount: 25, Continue: "eyJvIjoiIn0.c2ln"}, Items: []string{"a", "b"}}
+
+	req := httptest.NewRequest(http.MethodGet, "/users/foo?limit=2&continue=old", nil)
+	w := serve(func(c *gin.Context) { WriteResponse(c, nil, list) }, req)
+
+	want := `</users/foo?continue=eyJvIjoiIn0.c2ln&limit=2>; rel="next"`
+	if got := w.Header().Values("Link"); len(got) != 1 || got[0] != want {
+		t.Errorf("WriteResponse(): got links %q, want: %q", got, want)
+	}
+
+	if got, want := w.Body.String(), `{"code":200,"message":"success","data":{"totalCount":25,"continue":"eyJvIjoiIn0.c2ln","items":["a","b"]}}`; got != want {
+		t.Errorf("WriteResponse(): got body %q, want: %q", got, want)
+	}
+}
+
+type testUser struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Status   int    `json:"status"`
+	Password string `json:"password,omitempty"`
+}
+
+type testUserList struct {
+	metav1.ListMeta `json:",inline"`
+
+	Items []*testUser `json:"items"`
+}
+
+func TestWriteResponseFields(t *testing.T) {
+	user := &testUser{
+		TypeMeta:   metav1.TypeMeta{Kind: "User", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{ID: 12, InstanceID: "user-1", Name: "colin"},
+		Status:     1,
+		Password:   "******",
+	}
+	list := &testUserList{ListMeta: metav1.ListMeta{TotalCount: 1}, Items: []*testUser{user}}
+
+	tests := []struct {
+		data     interface{}
+		fields   []string
+		accept   string
+		wantBody string
+	}{
+		{
+			user, []string{"metadata.name", "metadata.id", "status"}, "",
+			`{"code":200,"message":"success","data":{"apiVersion":"v1","kind":"User","metadata":{"id":12,"name":"colin"},"status":1}}`,
+		},
+		{
+			list, []string{"metadata.name", "unknown.field"}, "",
+			`{"code":200,"message":"success","data":{"items":[{"apiVersion":"v1","kind":"User","metadata":{"name":"colin"}}],"totalCount":1}}`,
+		},
+		{
+			user, []string{"metadata"}, runtime.ContentTypeYAML,
+			"code: 200\ndata:\n  apiVersion: v1\n  kind: User\n  metadata:\n    createdAt: \"0001-01-01T00:00:00Z\"\n    id: 12\n    instanceID: user-1\n    name: colin\n    updatedAt: \"0001-01-01T00:00:00Z\"\nmessage: success\n",
+		},
+	}
+
+	for i, tt := range tests {
+		req := httptest.NewRequest(http.MethodGet, "/users/foo", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+
+		w := serve(func(c *gin.Context) { WriteResponse(c, nil, tt.data, WithFields(tt.fields)) }, req)
+		if got := w.Body.String(); got != tt.wantBody {
+			t.Errorf("WriteResponse(%d): got body %q, want: %q", i, got, tt.wantBody)
+		}
+	}
+}
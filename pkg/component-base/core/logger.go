@@ -0,0 +1,132 @@
+package core
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/klog/v2"
+
+	"github.com/xs0910/iam/pkg/component-base/util/iputil"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// XRequestIDKey defines X-Request-ID key string.
+const XRequestIDKey = "X-Request-ID"
+
+// Severity defines the severity of a logged error.
+type Severity string
+
+// Severities of the logged errors.
+const (
+	SeverityInfo    Severity = "info"
+	SeverityWarning Severity = "warning"
+	SeverityError   Severity = "error"
+)
+
+// SeverityForStatus returns the severity of an error which is responded with the HTTP status.
+func SeverityForStatus(status int) Severity {
+	switch {
+	case status >= http.StatusInternalServerError:
+		return SeverityError
+	case status >= http.StatusBadRequest:
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
+
+// LogEntry contains the information of an error written by WriteResponse.
+type LogEntry struct {
+	RequestID  string   `json:"requestID,omitempty"`
+	Method     string   `json:"method"`
+	Route      string   `json:"route"`
+	ClientIP   string   `json:"clientIP"`
+	HTTPStatus int      `json:"httpStatus"`
+	Code       int      `json:"code"`
+	Severity   Severity `json:"severity"`
+	// Error is the JSON formatted error, see errors.withCode.Format.
+	Error string `json:"error"`
+}
+
+// Logger logs the errors written by WriteResponse.
+type Logger interface {
+	LogError(entry *LogEntry)
+}
+
+// SuppressClientErrorStack controls whether the stack traces of errors responded
+// with a 4xx HTTP status are logged. Only the caller of the outermost error is
+// logged when it is true.
+var SuppressClientErrorStack = true
+
+var (
+	logger    Logger = klogLogger{}
+	loggerMux        = &sync.RWMutex{}
+)
+
+// SetLogger replaces the logger used by WriteResponse, nil restores the klog-backed default.
+func SetLogger(l Logger) {
+	if l == nil {
+		l = klogLogger{}
+	}
+
+	loggerMux.Lock()
+	defer loggerMux.Unlock()
+	logger = l
+}
+
+func getLogger() Logger {
+	loggerMux.RLock()
+	defer loggerMux.RUnlock()
+	return logger
+}
+
+// newLogEntry builds the log entry of err which is responded with coder.
+func newLogEntry(c *gin.Context, err error, coder errors.Coder) *LogEntry {
+	status := coder.HTTPStatus()
+
+	format := "%#+v"
+	if SuppressClientErrorStack && status >= http.StatusBadRequest && status < http.StatusInternalServerError {
+		format = "%#-v"
+	}
+
+	requestID := c.GetString(XRequestIDKey)
+	if requestID == "" {
+		requestID = c.GetHeader(XRequestIDKey)
+	}
+
+	return &LogEntry{
+		RequestID:  requestID,
+		Method:     c.Request.Method,
+		Route:      c.FullPath(),
+		ClientIP:   iputil.RemoteIP(c.Request),
+		HTTPStatus: status,
+		Code:       coder.Code(),
+		Severity:   SeverityForStatus(status),
+		Error:      fmt.Sprintf(format, err),
+	}
+}
+
+// klogLogger is the default Logger which logs errors with klog structured logging.
+type klogLogger struct{}
+
+func (klogLogger) LogError(entry *LogEntry) {
+	keysAndValues := []interface{}{
+		"requestID", entry.RequestID,
+		"method", entry.Method,
+		"route", entry.Route,
+		"clientIP", entry.ClientIP,
+		"httpStatus", entry.HTTPStatus,
+		"code", entry.Code,
+		"severity", entry.Severity,
+		"error", entry.Error,
+	}
+
+	if entry.Severity == SeverityError {
+		klog.ErrorSDepth(1, nil, "Request failed", keysAndValues...)
+		return
+	}
+
+	klog.InfoSDepth(1, "Request failed", keysAndValues...)
+}
@@ -0,0 +1,108 @@
+package core
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xs0910/iam/pkg/component-base/validation/field"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// ContentTypeProblemJSON is the media type of RFC 7807 problem details documents.
+const ContentTypeProblemJSON = "application/problem+json"
+
+// problemDetailsKey is the gin context key which enables problem details for a route.
+const problemDetailsKey = "core.problemDetails"
+
+// Problem defines an RFC 7807 problem details document. Code and Details are extension
+// members which carry the business error code and the field-level errors.
+type Problem struct {
+	// Type is a URI reference that identifies the problem type, it's the reference
+	// document of the error code, or "about:blank".
+	Type string `json:"type"`
+	// Title is a short, human-readable summary of the problem type.
+	Title string `json:"title"`
+	// Status is the HTTP status code of the response.
+	Status int `json:"status"`
+	// Instance is a URI reference that identifies the specific occurrence of the problem.
+	Instance string `json:"instance,omitempty"`
+	// Code define the business error code.
+	Code int `json:"code"`
+	// Details lists the invalid fields of the request.
+	Details []FieldDetail `json:"details,omitempty"`
+}
+
+// FieldDetail describes an invalid field of the request.
+type FieldDetail struct {
+	Field  string      `json:"field"`
+	Type   string      `json:"type"`
+	Value  interface{} `json:"value,omitempty"`
+	Detail string      `json:"detail,omitempty"`
+}
+
+// ProblemDetails returns a middleware which makes WriteResponse render the errors
+// of the routes it's applied to as problem details documents.
+// Problem details can also be requested per request with the Accept header.
+func ProblemDetails() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Set(problemDetailsKey, true)
+		c.Next()
+	}
+}
+
+// NewProblem converts err into a problem details document of the request.
+func NewProblem(c *gin.Context, err error) *Problem {
+	coder := errors.ParseCoder(err)
+
+	typ := coder.Reference()
+	if typ == "" {
+		typ = "about:blank"
+	}
+
+	return &Problem{
+		Type:     typ,
+		Title:    errors.LocalizedString(coder, c.GetHeader("Accept-Language")),
+		Status:   coder.HTTPStatus(),
+		Instance: c.Request.URL.RequestURI(),
+		Code:     coder.Code(),
+		Details:  fieldDetails(err),
+	}
+}
+
+// fieldDetails returns the details of the field errors in err's chain. The field errors
+// carried by a validation error take precedence, see errors.WithValidation.
+func fieldDetails(err error) []FieldDetail {
+	errs := errors.FieldErrors(err)
+	if errs == nil {
+		var agg errors.Aggregate
+		if errors.As(err, &agg) {
+			if flattened := errors.Flatten(agg); flattened != nil {
+				errs = flattened.Errors()
+			}
+		} else {
+			errs = []error{err}
+		}
+	}
+
+	var details []FieldDetail
+	for _, e := range errs {
+		var fe *field.Error
+		if !errors.As(e, &fe) {
+			continue
+		}
+
+		details = append(details, FieldDetail{
+			Field:  fe.Field,
+			Type:   string(fe.Type),
+			Value:  fe.BadValue,
+			Detail: fe.Detail,
+		})
+	}
+
+	return details
+}
+
+// wantsProblem reports whether the error of the request should be rendered as
+// problem details, mediaType is the media type negotiated for errors.
+func wantsProblem(c *gin.Context, mediaType string) bool {
+	return mediaType == ContentTypeProblemJSON || c.GetBool(problemDetailsKey)
+}
@@ -0,0 +1,119 @@
+package core
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/xs0910/iam/pkg/component-base/validation/field"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+const errCoreValidation int = 990010
+
+func init() {
+	errors.Register(errors.NewCoder(errCoreValidation, http.StatusBadRequest, "Validation failed",
+		"https://example.com/errors#990010"))
+}
+
+func TestWriteResponseProblem(t *testing.T) {
+	SetLogger(&fakeLogger{})
+	defer SetLogger(nil)
+
+	fldPath := field.NewPath("metadata")
+	fieldErrs := field.ErrorList{
+		field.Required(fldPath.Child("name"), ""),
+		field.Invalid(fldPath.Child("instanceID"), "x y", "must not contain spaces"),
+	}
+
+	tests := []struct {
+		name       string
+		accept     string
+		middleware bool
+		err        error
+		want       *Problem
+	}{
+		{
+			name:   "accept header",
+			accept: "application/problem+json",
+			err:    errors.WithCode(errCoreNotFound, "user foo not found"),
+			want: &Problem{
+				Type: "about:blank", Title: "Not found", Status: http.StatusNotFound,
+				Instance: "/users/foo?x=1", Code: errCoreNotFound,
+			},
+		},
+		{
+			name:       "route middleware",
+			middleware: true,
+			err:        errors.WrapC(fieldErrs.ToAggregate(), errCoreValidation, "validate user"),
+			want: &Problem{
+				Type: "https://example.com/errors#990010", Title: "Validation failed", Status: http.StatusBadRequest,
+				Instance: "/users/foo?x=1", Code: errCoreValidation,
+				Details: []FieldDetail{
+					{Field: "metadata.name", Type: string(field.ErrorTypeRequired), Value: ""},
+					{Field: "metadata.instanceID", Type: string(field.ErrorTypeInvalid), Value: "x y", Detail: "must not contain spaces"},
+				},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		r := gin.New()
+		if tt.middleware {
+			r.Use(ProblemDetails())
+		}
+		r.GET("/users/:name", func(c *gin.Context) { WriteResponse(c, tt.err, nil) })
+
+		req := httptest.NewRequest(http.MethodGet, "/users/foo?x=1", nil)
+		if tt.accept != "" {
+			req.Header.Set("Accept", tt.accept)
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+
+		if got := w.Header().Get("Content-Type"); got != ContentTypeProblemJSON+"; charset=utf-8" {
+			t.Errorf("%s: got content type %q, want: %q", tt.name, got, ContentTypeProblemJSON)
+		}
+
+		got := &Problem{}
+		if err := json.Unmarshal(w.Body.Bytes(), got); err != nil {
+			t.Fatalf("%s: invalid body %q: %v", tt.name, w.Body.String(), err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want: %+v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestWriteResponseNoProblem(t *testing.T) {
+	SetLogger(&fakeLogger{})
+	defer SetLogger(nil)
+
+	req := httptest.NewRequest(http.MethodGet, "/users/foo", nil)
+	req.Header.Set("Accept", "application/json, application/problem+json")
+	w := serve(func(c *gin.Context) { WriteResponse(c, errors.WithCode(errCoreNotFound, "not found"), nil) }, req)
+
+	if got := w.Header().Get("Content-Type"); got != "application/json; charset=utf-8" {
+		t.Errorf("WriteResponse(): got content type %q, want: %q", got, "application/json")
+	}
+}
+
+func TestWriteResponseDetails(t *testing.T) {
+	SetLogger(&fakeLogger{})
+	defer SetLogger(nil)
+
+	fieldErrs := field.ErrorList{field.Invalid(field.NewPath("name"), "Foo", "must be lower case")}
+	err := errors.WithValidation(errCoreValidation, fieldErrs.ToAggregate())
+
+	w := serve(func(c *gin.Context) { WriteResponse(c, err, nil) }, httptest.NewRequest(http.MethodGet, "/users/foo", nil))
+
+	want := `{"code":990010,"message":"Validation failed","data":null,"reference":"https://example.com/errors#990010",` +
+		`"details":[{"field":"name","type":"FieldValueInvalid","value":"Foo","detail":"must be lower case"}]}`
+	if got := w.Body.String(); got != want {
+		t.Errorf("WriteResponse():\n got: %s\nwant: %s", got, want)
+	}
+}
@@ -154,6 +154,10 @@ func (meta *TypeMeta) GroupVersionKind() scheme.GroupVersionKind {
 // various status objects. A resource may have only one of {ObjectMeta, ListMeta}.
 type ListMeta struct {
 	TotalCount int64 `json:"totalCount,omitempty"`
+
+	// Continue is the opaque token of the next page, set if the list is paginated by
+	// ListOptions.Continue and more objects are available.
+	Continue string `json:"continue,omitempty"`
 }
 
 func (meta *ListMeta) GetTotalCount() int64 {
@@ -164,4 +168,12 @@ func (meta *ListMeta) SetTotalCount(count int64) {
 	meta.TotalCount = count
 }
 
+func (meta *ListMeta) GetContinue() string {
+	return meta.Continue
+}
+
+func (meta *ListMeta) SetContinue(c string) {
+	meta.Continue = c
+}
+
 func (meta *ListMeta) GetListMeta() ListInterface { return meta }
@@ -1,5 +1,9 @@
 package v1
 
+// DryRunAll is the dry-run directive of CreateOptions, UpdateOptions and PatchOptions
+// which processes all the stages of the request without persisting it.
+const DryRunAll = "All"
+
 // ListOptions is the query options to a standard REST list call.
 type ListOptions struct {
 	TypeMeta `json:",inline"`
@@ -18,6 +22,18 @@ type ListOptions struct {
 
 	// Limit specify the number of records to be retrieved.
 	Limit *int64 `json:"limit,omitempty" form:"limit"`
+
+	// Continue is the opaque token returned in ListMeta.Continue by the previous call, to
+	// retrieve the next page after the records it returned. Offset is ignored when set.
+	Continue string `json:"continue,omitempty" form:"continue"`
+
+	// SortBy is the comma-separated fields the records are sorted by, in ascending order
+	// unless prefixed by `-`, e.g. `name,-createdAt`.
+	SortBy string `json:"sortBy,omitempty" form:"sortBy"`
+
+	// Fields is the comma-separated fields of the records to be returned, all the fields
+	// are returned if empty.
+	Fields string `json:"fields,omitempty" form:"fields"`
 }
 
 // ExportOptions is the query options to the standard REST get call.
@@ -31,8 +31,16 @@ type Type interface {
 type ListInterface interface {
 	GetTotalCount() int64
 	SetTotalCount(count int64)
+	GetContinue() string
+	SetContinue(c string)
+}
+
+// ListMetaAccessor retrieves the list metadata of list objects which embed ListMeta.
+type ListMetaAccessor interface {
+	GetListMeta() ListInterface
 }
 
 var _ Object = &ObjectMeta{}
 var _ Type = &TypeMeta{}
 var _ ListInterface = &ListMeta{}
+var _ ListMetaAccessor = &ListMeta{}
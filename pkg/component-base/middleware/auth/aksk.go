@@ -0,0 +1,13 @@
+package auth
+
+import (
+	"github.com/gin-gonic/gin"
+
+	"github.com/xs0910/iam/pkg/component-base/auth"
+)
+
+// AKSK returns a middleware which authenticates the requests signed by auth.RequestSigner
+// with verifier, it's Middleware with an AKSKStrategy.
+func AKSK(verifier *auth.RequestVerifier) gin.HandlerFunc {
+	return Middleware(NewAKSKStrategy(verifier))
+}
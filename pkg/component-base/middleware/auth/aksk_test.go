@@ -0,0 +1,66 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/xs0910/iam/pkg/component-base/auth"
+	"github.com/xs0910/iam/pkg/component-base/core"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+	core.SetLogger(discardLogger{})
+}
+
+type discardLogger struct{}
+
+func (discardLogger) LogError(*core.LogEntry) {}
+
+var testSecrets = auth.SecretKeyStore{"secret-id": "secret-key"}
+
+// serve runs the middleware in front of a handler writing the principal.
+func serve(middleware gin.HandlerFunc, req *http.Request) *httptest.ResponseRecorder {
+	r := gin.New()
+	r.POST("/v1/users", middleware, func(c *gin.Context) {
+		p, _ := GetPrincipal(c)
+		c.String(http.StatusOK, "%s:%s", p.Strategy, p.Name)
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+
+	return w
+}
+
+func TestAKSK(t *testing.T) {
+	middleware := AKSK(auth.NewRequestVerifier(testSecrets))
+
+	req := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(`{"name":"colin"}`))
+	if err := auth.NewRequestSigner("secret-id", "secret-key").Sign(req); err != nil {
+		t.Fatal(err)
+	}
+
+	w := serve(middleware, req)
+	if w.Code != http.StatusOK || w.Body.String() != "aksk:secret-id" {
+		t.Errorf("got %d %s", w.Code, w.Body.String())
+	}
+
+	// replayed request.
+	replayed := httptest.NewRequest(http.MethodPost, "/v1/users", strings.NewReader(`{"name":"colin"}`))
+	replayed.Header = req.Header.Clone()
+
+	w = serve(middleware, replayed)
+	var resp core.Response
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if w.Code != http.StatusUnauthorized || resp.Code != auth.ErrNonceReused {
+		t.Errorf("got %d %s, want code %d", w.Code, w.Body.String(), auth.ErrNonceReused)
+	}
+}
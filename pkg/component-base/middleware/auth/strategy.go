@@ -0,0 +1,271 @@
+// Package auth provides the gin middlewares authenticating requests.
+package auth
+
+import (
+	"net/http"
+	"strings"
+	"sync"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+
+	"github.com/xs0910/iam/pkg/component-base/auth"
+	"github.com/xs0910/iam/pkg/component-base/core"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// PrincipalKey is the key of the Principal of an authenticated request in the gin context.
+const PrincipalKey = "principal"
+
+// Principal is the identity of an authenticated request.
+type Principal struct {
+	// Name is the username, or the secretID of signed requests.
+	Name string `json:"name"`
+	// Strategy is the name of the strategy which authenticated the request.
+	Strategy string `json:"strategy"`
+	// Claims are the claims of the token of requests authenticated by JWTStrategy.
+	Claims jwt.MapClaims `json:"claims,omitempty"`
+}
+
+// GetPrincipal returns the Principal of the request authenticated by Middleware.
+func GetPrincipal(c *gin.Context) (*Principal, bool) {
+	value, ok := c.Get(PrincipalKey)
+	if !ok {
+		return nil, false
+	}
+
+	p, ok := value.(*Principal)
+	return p, ok
+}
+
+// Strategy authenticates requests.
+type Strategy interface {
+	// Authenticate returns the Principal of the request of c, or a withCode error
+	// if the request is not authenticated.
+	Authenticate(c *gin.Context) (*Principal, error)
+}
+
+// Challenger is implemented by the strategies which challenge unauthenticated clients
+// with a WWW-Authenticate header.
+type Challenger interface {
+	// Challenge returns the value of the WWW-Authenticate header.
+	Challenge() string
+}
+
+// Middleware returns a middleware which authenticates the requests with strategy and sets
+// their Principal in the gin context. The requests which fail to authenticate are aborted,
+// and the error is written by core.WriteResponse.
+func Middleware(strategy Strategy) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		principal, err := strategy.Authenticate(c)
+		if err != nil {
+			if challenger, ok := strategy.(Challenger); ok && errors.ParseCoder(err).HTTPStatus() == http.StatusUnauthorized {
+				if challenge := challenger.Challenge(); challenge != "" {
+					c.Header("WWW-Authenticate", challenge)
+				}
+			}
+			core.WriteResponse(c, err, nil)
+			c.Abort()
+
+			return
+		}
+
+		c.Set(PrincipalKey, principal)
+		c.Next()
+	}
+}
+
+// parseAuthorization returns the scheme and the credentials of the Authorization header of c.
+func parseAuthorization(c *gin.Context) (scheme, credentials string, err error) {
+	header := c.GetHeader("Authorization")
+	if header == "" {
+		return "", "", errors.WithCode(auth.ErrMissingAuthHeader, "missing Authorization header")
+	}
+
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[1]) == "" {
+		return "", "", errors.WithCode(auth.ErrInvalidAuthHeader, "malformed Authorization header")
+	}
+
+	return parts[0], strings.TrimSpace(parts[1]), nil
+}
+
+// JWTStrategy authenticates the requests with a bearer token, the name of the Principal is
+// the `sub` claim of the token.
+type JWTStrategy struct {
+	verifier *auth.Verifier
+}
+
+var _ Strategy = &JWTStrategy{}
+
+// NewJWTStrategy returns a JWTStrategy verifying tokens with verifier, e.g. a verifier
+// looking up the secretKeys of the secretIDs in the secret store.
+func NewJWTStrategy(verifier *auth.Verifier) *JWTStrategy {
+	return &JWTStrategy{verifier: verifier}
+}
+
+// Authenticate verifies the bearer token of the request.
+func (s *JWTStrategy) Authenticate(c *gin.Context) (*Principal, error) {
+	scheme, token, err := parseAuthorization(c)
+	if err != nil {
+		return nil, err
+	}
+	if !strings.EqualFold(scheme, "Bearer") {
+		return nil, errors.WithCode(auth.ErrInvalidAuthHeader, "unexpected authorization scheme %s", scheme)
+	}
+
+	claims, err := s.verifier.Verify(token)
+	if err != nil {
+		return nil, err
+	}
+
+	sub, _ := claims["sub"].(string)
+	return &Principal{Name: sub, Strategy: "jwt", Claims: claims}, nil
+}
+
+// Challenge returns the Bearer challenge.
+func (s *JWTStrategy) Challenge() string {
+	return "Bearer"
+}
+
+// PasswordLookup returns the password hashed by auth.Encrypt of username.
+// It returns an empty hash without error if username does not exist.
+type PasswordLookup func(username string) (string, error)
+
+// BasicStrategy authenticates the requests with the HTTP Basic scheme, the passwords are
+// compared with auth.Compare.
+type BasicStrategy struct {
+	lookup PasswordLookup
+	realm  string
+}
+
+var _ Strategy = &BasicStrategy{}
+
+// NewBasicStrategy returns a BasicStrategy looking up the hashed passwords with lookup.
+func NewBasicStrategy(lookup PasswordLookup, realm string) *BasicStrategy {
+	return &BasicStrategy{lookup: lookup, realm: realm}
+}
+
+// Authenticate compares the password of the request with the hashed password of the user.
+func (s *BasicStrategy) Authenticate(c *gin.Context) (*Principal, error) {
+	scheme, _, err := parseAuthorization(c)
+	if err != nil {
+		return nil, err
+	}
+	username, password, ok := c.Request.BasicAuth()
+	if !ok || !strings.EqualFold(scheme, "Basic") {
+		return nil, errors.WithCode(auth.ErrInvalidAuthHeader, "malformed basic credentials")
+	}
+
+	hashedPassword, err := s.lookup(username)
+	if err != nil {
+		return nil, errors.Wrapf(err, "look up user %s", username)
+	}
+
+	// the password of an unknown user is compared with a dummy hash too, so the timing
+	// of the response doesn't reveal whether the user exists.
+	found := hashedPassword != ""
+	if !found {
+		hashedPassword = dummyPasswordHash()
+	}
+
+	if err := auth.Compare(hashedPassword, password); err != nil || !found {
+		return nil, errors.WithCode(auth.ErrPasswordIncorrect, "username or password of user %s is incorrect", username)
+	}
+
+	return &Principal{Name: username, Strategy: "basic"}, nil
+}
+
+var (
+	dummyHashOnce sync.Once
+	dummyHash     string
+)
+
+// dummyPasswordHash returns the hash compared with the passwords of unknown users, it's
+// hashed by auth.Encrypt like the passwords of the users so the comparison is as slow.
+func dummyPasswordHash() string {
+	dummyHashOnce.Do(func() {
+		dummyHash, _ = auth.Encrypt("iam-dummy-password")
+	})
+
+	return dummyHash
+}
+
+// Challenge returns the Basic challenge of the realm.
+func (s *BasicStrategy) Challenge() string {
+	return `Basic realm="` + s.realm + `"`
+}
+
+// AKSKStrategy authenticates the requests signed by auth.RequestSigner, the name of the
+// Principal is the secretID of the request.
+type AKSKStrategy struct {
+	verifier *auth.RequestVerifier
+}
+
+var _ Strategy = &AKSKStrategy{}
+
+// NewAKSKStrategy returns an AKSKStrategy verifying requests with verifier.
+func NewAKSKStrategy(verifier *auth.RequestVerifier) *AKSKStrategy {
+	return &AKSKStrategy{verifier: verifier}
+}
+
+// Authenticate verifies the signature of the request.
+func (s *AKSKStrategy) Authenticate(c *gin.Context) (*Principal, error) {
+	secretID, err := s.verifier.Verify(c.Request)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Principal{Name: secretID, Strategy: "aksk"}, nil
+}
+
+// Challenge returns the challenge of signed requests.
+func (s *AKSKStrategy) Challenge() string {
+	return auth.SignatureAlgorithm
+}
+
+// AutoStrategy authenticates the requests with the strategy of the scheme of their
+// Authorization header: Bearer, Basic or auth.SignatureAlgorithm.
+// The schemes of nil strategies are not supported.
+type AutoStrategy struct {
+	JWT   *JWTStrategy
+	Basic *BasicStrategy
+	AKSK  *AKSKStrategy
+}
+
+var _ Strategy = &AutoStrategy{}
+
+// Authenticate authenticates the request with the strategy of its scheme.
+func (s *AutoStrategy) Authenticate(c *gin.Context) (*Principal, error) {
+	scheme, _, err := parseAuthorization(c)
+	if err != nil {
+		return nil, err
+	}
+
+	switch {
+	case strings.EqualFold(scheme, "Bearer") && s.JWT != nil:
+		return s.JWT.Authenticate(c)
+	case strings.EqualFold(scheme, "Basic") && s.Basic != nil:
+		return s.Basic.Authenticate(c)
+	case scheme == auth.SignatureAlgorithm && s.AKSK != nil:
+		return s.AKSK.Authenticate(c)
+	}
+
+	return nil, errors.WithCode(auth.ErrInvalidAuthHeader, "unsupported authorization scheme %s", scheme)
+}
+
+// Challenge returns the challenges of the supported schemes.
+func (s *AutoStrategy) Challenge() string {
+	var challenges []string
+	if s.JWT != nil {
+		challenges = append(challenges, s.JWT.Challenge())
+	}
+	if s.Basic != nil {
+		challenges = append(challenges, s.Basic.Challenge())
+	}
+	if s.AKSK != nil {
+		challenges = append(challenges, s.AKSK.Challenge())
+	}
+
+	return strings.Join(challenges, ", ")
+}
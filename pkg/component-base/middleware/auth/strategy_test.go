@@ -0,0 +1,126 @@
+package auth
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/xs0910/iam/pkg/component-base/auth"
+	"github.com/xs0910/iam/pkg/component-base/core"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+func newAutoStrategy(t *testing.T) *AutoStrategy {
+	hashed, err := (&auth.BcryptHasher{Cost: 4}).Hash("Passw0rd!")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lookup := func(username string) (string, error) {
+		switch username {
+		case "colin":
+			return hashed, nil
+		case "broken":
+			return "", errors.New("database is down")
+		}
+		return "", nil
+	}
+
+	return &AutoStrategy{
+		JWT:   NewJWTStrategy(auth.NewVerifier(testSecrets)),
+		Basic: NewBasicStrategy(lookup, "iam"),
+		AKSK:  NewAKSKStrategy(auth.NewRequestVerifier(testSecrets)),
+	}
+}
+
+func TestAutoStrategy(t *testing.T) {
+	token, err := auth.Sign("secret-id", "secret-key", "iam-apiserver", "iam", auth.WithSubject("colin"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name       string
+		setup      func(req *http.Request)
+		wantStatus int
+		wantBody   string
+		wantCode   int
+	}{
+		{"jwt", func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token) }, http.StatusOK, "jwt:colin", 0},
+		{"jwt lowercase scheme", func(req *http.Request) { req.Header.Set("Authorization", "bearer "+token) }, http.StatusOK, "jwt:colin", 0},
+		{"basic", func(req *http.Request) { req.SetBasicAuth("colin", "Passw0rd!") }, http.StatusOK, "basic:colin", 0},
+		{"aksk", func(req *http.Request) {
+			if err := auth.NewRequestSigner("secret-id", "secret-key").Sign(req); err != nil {
+				t.Fatal(err)
+			}
+		}, http.StatusOK, "aksk:secret-id", 0},
+		{"missing header", func(req *http.Request) {}, http.StatusUnauthorized, "", auth.ErrMissingAuthHeader},
+		{"unsupported scheme", func(req *http.Request) { req.Header.Set("Authorization", "Digest foo") }, http.StatusUnauthorized, "", auth.ErrInvalidAuthHeader},
+		{"invalid token", func(req *http.Request) { req.Header.Set("Authorization", "Bearer "+token+"x") }, http.StatusUnauthorized, "", auth.ErrSignatureInvalid},
+		{"wrong password", func(req *http.Request) { req.SetBasicAuth("colin", "password") }, http.StatusUnauthorized, "", auth.ErrPasswordIncorrect},
+		{"unknown user", func(req *http.Request) { req.SetBasicAuth("unknown", "Passw0rd!") }, http.StatusUnauthorized, "", auth.ErrPasswordIncorrect},
+		{"lookup failure", func(req *http.Request) { req.SetBasicAuth("broken", "Passw0rd!") }, http.StatusInternalServerError, "", 1},
+	}
+
+	middleware := Middleware(newAutoStrategy(t))
+	for _, tt := range tests {
+		req := httptest.NewRequest(http.MethodPost, "/v1/users", nil)
+		tt.setup(req)
+
+		w := serve(middleware, req)
+		if w.Code != tt.wantStatus {
+			t.Errorf("%s: got status %d, want %d: %s", tt.name, w.Code, tt.wantStatus, w.Body.String())
+			continue
+		}
+
+		if tt.wantCode == 0 {
+			if w.Body.String() != tt.wantBody {
+				t.Errorf("%s: got %s, want %s", tt.name, w.Body.String(), tt.wantBody)
+			}
+			continue
+		}
+
+		var resp core.Response
+		if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+			t.Fatal(err)
+		}
+		if resp.Code != tt.wantCode {
+			t.Errorf("%s: got code %d, want %d", tt.name, resp.Code, tt.wantCode)
+		}
+		if tt.wantStatus == http.StatusUnauthorized {
+			if got := w.Header().Get("WWW-Authenticate"); got != `Bearer, Basic realm="iam", IAM-HMAC-SHA256` {
+				t.Errorf("%s: got WWW-Authenticate %q", tt.name, got)
+			}
+		}
+	}
+}
+
+func TestBasicStrategyUnknownUser(t *testing.T) {
+	if err := auth.Compare(dummyPasswordHash(), "iam-dummy-password"); err != nil {
+		t.Fatalf("got an invalid dummy hash: %v", err)
+	}
+
+	basic := newAutoStrategy(t).Basic
+	authenticate := func(username, password string) error {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+		c.Request.SetBasicAuth(username, password)
+
+		_, err := basic.Authenticate(c)
+		return err
+	}
+
+	// an unknown user and a wrong password can't be told apart.
+	unknown := authenticate("unknown", "Passw0rd!")
+	wrong := authenticate("colin", "password")
+	if !errors.IsCode(unknown, auth.ErrPasswordIncorrect) || !errors.IsCode(wrong, auth.ErrPasswordIncorrect) {
+		t.Fatalf("got errors %v and %v", unknown, wrong)
+	}
+	if strings.Replace(unknown.Error(), "unknown", "colin", 1) != wrong.Error() {
+		t.Errorf("got different errors %q and %q", unknown, wrong)
+	}
+}
@@ -0,0 +1,115 @@
+package runtime
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/xs0910/iam/pkg/component-base/json"
+)
+
+// fieldsEncoder omits the fields of the objects which are not requested.
+type fieldsEncoder struct {
+	encoder Encoder
+	fields  []string
+}
+
+// NewFieldsEncoder returns an Encoder which encodes the objects with encoder, keeping only
+// their kind, apiVersion and the requested fields, which are JSON paths like `metadata.name`.
+// The items of lists are projected, not the lists themselves. All the fields are kept if
+// fields is empty.
+func NewFieldsEncoder(encoder Encoder, fields []string) Encoder {
+	return &fieldsEncoder{encoder: encoder, fields: fields}
+}
+
+func (e *fieldsEncoder) Encode(v interface{}) ([]byte, error) {
+	projected, err := SelectFields(v, e.fields)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.encoder.Encode(projected)
+}
+
+// SelectFields returns the JSON form of obj, keeping only the kind, apiVersion and the
+// requested fields of the objects, see NewFieldsEncoder. obj is returned as is if fields is empty.
+func SelectFields(obj interface{}, fields []string) (interface{}, error) {
+	if len(fields) == 0 {
+		return obj, nil
+	}
+
+	data, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+
+	paths := make([][]string, 0, len(fields))
+	for _, f := range fields {
+		paths = append(paths, strings.Split(f, "."))
+	}
+
+	return selectObject(generic, paths), nil
+}
+
+// selectObject keeps the paths of obj, an object, a list of objects or an array of them.
+func selectObject(obj interface{}, paths [][]string) interface{} {
+	switch v := obj.(type) {
+	case []interface{}:
+		for i := range v {
+			v[i] = selectObject(v[i], paths)
+		}
+
+		return v
+	case map[string]interface{}:
+		if items, ok := v["items"].([]interface{}); ok {
+			v["items"] = selectObject(items, paths)
+			return v
+		}
+
+		selected := selectPaths(v, paths)
+		for _, key := range []string{"kind", "apiVersion"} {
+			if value, ok := v[key]; ok {
+				selected[key] = value
+			}
+		}
+
+		return selected
+	default:
+		return obj
+	}
+}
+
+// selectPaths returns the members of obj at paths.
+func selectPaths(obj map[string]interface{}, paths [][]string) map[string]interface{} {
+	selected := map[string]interface{}{}
+	nested := map[string][][]string{}
+	for _, path := range paths {
+		if _, ok := obj[path[0]]; !ok {
+			continue
+		}
+
+		if len(path) == 1 {
+			selected[path[0]] = obj[path[0]]
+		} else {
+			nested[path[0]] = append(nested[path[0]], path[1:])
+		}
+	}
+
+	// the paths sharing a prefix are selected together, e.g. metadata.name and metadata.id.
+	for key, rest := range nested {
+		if _, ok := selected[key]; ok {
+			continue
+		}
+		if member, ok := obj[key].(map[string]interface{}); ok {
+			selected[key] = selectPaths(member, rest)
+		}
+	}
+
+	return selected
+}
@@ -0,0 +1,97 @@
+package runtime
+
+import (
+	"testing"
+
+	"github.com/xs0910/iam/pkg/component-base/json"
+)
+
+type testMeta struct {
+	ID     uint64            `json:"id,omitempty"`
+	Name   string            `json:"name,omitempty"`
+	Labels map[string]string `json:"labels,omitempty"`
+}
+
+type testUser struct {
+	Kind       string   `json:"kind,omitempty"`
+	APIVersion string   `json:"apiVersion,omitempty"`
+	Metadata   testMeta `json:"metadata,omitempty"`
+	Status     int      `json:"status"`
+	Password   string   `json:"password,omitempty"`
+}
+
+type testUserList struct {
+	TotalCount int64       `json:"totalCount"`
+	Items      []*testUser `json:"items"`
+}
+
+func TestSelectFields(t *testing.T) {
+	user := &testUser{
+		Kind:       "User",
+		APIVersion: "v1",
+		Metadata:   testMeta{ID: 12, Name: "colin", Labels: map[string]string{"env": "dev"}},
+		Status:     1,
+		Password:   "******",
+	}
+	list := &testUserList{TotalCount: 2, Items: []*testUser{user, {Metadata: testMeta{ID: 13, Name: "lily"}}}}
+
+	tests := []struct {
+		name   string
+		obj    interface{}
+		fields []string
+		want   string
+	}{
+		{
+			"nested paths", user, []string{"metadata.name", "metadata.id", "metadata.labels.env"},
+			`{"apiVersion":"v1","kind":"User","metadata":{"id":12,"labels":{"env":"dev"},"name":"colin"}}`,
+		},
+		{
+			"whole member", user, []string{"metadata", "metadata.name", "status"},
+			`{"apiVersion":"v1","kind":"User","metadata":{"id":12,"labels":{"env":"dev"},"name":"colin"},"status":1}`,
+		},
+		{
+			"list", list, []string{"metadata.name"},
+			`{"items":[{"apiVersion":"v1","kind":"User","metadata":{"name":"colin"}},{"metadata":{"name":"lily"}}],"totalCount":2}`,
+		},
+		{
+			"array", []*testUser{user}, []string{"status"},
+			`[{"apiVersion":"v1","kind":"User","status":1}]`,
+		},
+		{
+			"unknown paths", user, []string{"unknown", "metadata.unknown", "status.code"},
+			`{"apiVersion":"v1","kind":"User","metadata":{}}`,
+		},
+		{
+			"no fields", user, nil,
+			`{"kind":"User","apiVersion":"v1","metadata":{"id":12,"name":"colin","labels":{"env":"dev"}},"status":1,"password":"******"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		selected, err := SelectFields(tt.obj, tt.fields)
+		if err != nil {
+			t.Errorf("%s: SelectFields(): %v", tt.name, err)
+			continue
+		}
+
+		got, err := json.Marshal(selected)
+		if err != nil || string(got) != tt.want {
+			t.Errorf("%s: got (%s, %v), want: %s", tt.name, got, err, tt.want)
+		}
+	}
+}
+
+func TestFieldsEncoder(t *testing.T) {
+	encoder, _ := NewSimpleClientNegotiator().Encoder()
+	user := &testUser{Kind: "User", Metadata: testMeta{ID: 12, Name: "colin"}, Password: "******"}
+
+	got, err := NewFieldsEncoder(encoder, []string{"metadata.name"}).Encode(user)
+	want := `{"kind":"User","metadata":{"name":"colin"}}`
+	if err != nil || string(got) != want {
+		t.Errorf("Encode(): got (%s, %v), want: %s", got, err, want)
+	}
+
+	if _, err := NewFieldsEncoder(encoder, []string{"status"}).Encode(func() {}); err == nil {
+		t.Error("Encode(): got no error for a value which is not JSON")
+	}
+}
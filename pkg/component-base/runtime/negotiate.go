@@ -1,10 +1,30 @@
 package runtime
 
 import (
+	"bytes"
 	"fmt"
+	"mime"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+
 	"github.com/xs0910/iam/pkg/component-base/json"
 )
 
+// Media types supported by the ClientNegotiator returned by NewClientNegotiator.
+const (
+	ContentTypeJSON = "application/json"
+	ContentTypeYAML = "application/yaml"
+	// ContentTypeCompactJSON is encoded as JSON, it's up to the caller what is compacted.
+	ContentTypeCompactJSON = "application/vnd.iam.compact+json"
+)
+
+// SupportedMediaTypes lists the media types supported by NewClientNegotiator in
+// order of preference.
+var SupportedMediaTypes = []string{ContentTypeJSON, ContentTypeYAML, ContentTypeCompactJSON}
+
 // NegotiateError is returned when a ClientNegotiator is unable to locate a serializer for the requested operation.
 type NegotiateError struct {
 	ContentType string
@@ -44,3 +64,185 @@ func (a *apimachineryClientNegotiatorSerializer) Encode(v interface{}) ([]byte,
 func NewSimpleClientNegotiator() ClientNegotiator {
 	return &apimachineryClientNegotiator{}
 }
+
+type mediaTypeClientNegotiator struct {
+	mediaType string
+}
+
+var _ ClientNegotiator = &mediaTypeClientNegotiator{}
+
+func (n *mediaTypeClientNegotiator) Encoder() (Encoder, error) {
+	switch n.mediaType {
+	case ContentTypeJSON, ContentTypeCompactJSON:
+		return &apimachineryClientNegotiatorSerializer{}, nil
+	case ContentTypeYAML:
+		return yamlSerializer{}, nil
+	}
+
+	return nil, NegotiateError{ContentType: n.mediaType}
+}
+
+func (n *mediaTypeClientNegotiator) Decoder() (Decoder, error) {
+	switch n.mediaType {
+	case ContentTypeJSON, ContentTypeCompactJSON:
+		return &apimachineryClientNegotiatorSerializer{}, nil
+	case ContentTypeYAML:
+		return yamlSerializer{}, nil
+	}
+
+	return nil, NegotiateError{ContentType: n.mediaType}
+}
+
+// NewClientNegotiator returns a ClientNegotiator for the media type, one of SupportedMediaTypes.
+// Encoder and Decoder return a NegotiateError for other media types.
+func NewClientNegotiator(mediaType string) ClientNegotiator {
+	return &mediaTypeClientNegotiator{mediaType: mediaType}
+}
+
+// yamlSerializer converts objects to YAML through their JSON form, so the json tags
+// of the objects are honored.
+type yamlSerializer struct{}
+
+func (yamlSerializer) Encode(v interface{}) ([]byte, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var obj interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&obj); err != nil {
+		return nil, err
+	}
+
+	return yaml.Marshal(convertNumbers(obj))
+}
+
+func (yamlSerializer) Decode(data []byte, v interface{}) error {
+	var obj interface{}
+	if err := yaml.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+
+	js, err := json.Marshal(convertYAMLMaps(obj))
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(js, v)
+}
+
+// convertNumbers replaces the JSON numbers in obj by int64 or float64 values,
+// so that YAML does not quote them.
+func convertNumbers(obj interface{}) interface{} {
+	switch v := obj.(type) {
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = convertNumbers(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = convertNumbers(value)
+		}
+	case interface {
+		Int64() (int64, error)
+		Float64() (float64, error)
+		String() string
+	}:
+		if i, err := v.Int64(); err == nil {
+			return i
+		}
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return u
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+	}
+
+	return obj
+}
+
+// convertYAMLMaps replaces the map[interface{}]interface{} decoded by YAML with
+// map[string]interface{}, which can be encoded as JSON.
+func convertYAMLMaps(obj interface{}) interface{} {
+	switch v := obj.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			m[fmt.Sprintf("%v", key)] = convertYAMLMaps(value)
+		}
+		return m
+	case []interface{}:
+		for i, value := range v {
+			v[i] = convertYAMLMaps(value)
+		}
+	}
+
+	return obj
+}
+
+// NegotiateMediaType returns the media type in supported which matches best with accept,
+// a value of the HTTP Accept header. ok is false if none of supported is acceptable.
+// An empty accept accepts the first supported media type.
+func NegotiateMediaType(accept string, supported []string) (mediaType string, ok bool) {
+	if len(supported) == 0 {
+		return "", false
+	}
+
+	if strings.TrimSpace(accept) == "" {
+		return supported[0], true
+	}
+
+	type clause struct {
+		mediaType string
+		q         float64
+	}
+
+	var clauses []clause
+	for _, part := range strings.Split(accept, ",") {
+		mt, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		q := 1.0
+		if qs, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(qs, 64); err != nil {
+				continue
+			}
+		}
+		if q <= 0 {
+			continue
+		}
+
+		clauses = append(clauses, clause{mediaType: mt, q: q})
+	}
+
+	sort.SliceStable(clauses, func(i, j int) bool { return clauses[i].q > clauses[j].q })
+
+	for _, c := range clauses {
+		for _, s := range supported {
+			if mediaTypeMatches(c.mediaType, s) {
+				return s, true
+			}
+		}
+	}
+
+	return "", false
+}
+
+// mediaTypeMatches reports whether the accepted media type, which may contain wildcards,
+// matches the media type mt.
+func mediaTypeMatches(accepted, mt string) bool {
+	if accepted == "*/*" || accepted == mt {
+		return true
+	}
+
+	if strings.HasSuffix(accepted, "/*") {
+		return strings.HasPrefix(mt, strings.TrimSuffix(accepted, "*"))
+	}
+
+	return false
+}
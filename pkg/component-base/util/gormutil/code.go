@@ -0,0 +1,9 @@
+package gormutil
+
+//go:generate codegen --type=int --output code_generated.go
+
+// Errors of the list and write options.
+const (
+	// ErrValidation - 400: Validation failed.
+	ErrValidation int = iota + 100301
+)
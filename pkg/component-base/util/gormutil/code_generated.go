@@ -0,0 +1,10 @@
+// Code generated by "codegen --type=int --output code_generated.go"; DO NOT EDIT.
+
+package gormutil
+
+import "github.com/xs0910/iam/pkg/errors"
+
+// init register error codes defines in this source code to `github.com/xs0910/iam/pkg/errors`
+func init() {
+	errors.MustRegister(errors.NewCoder(ErrValidation, 400, "Validation failed", ""))
+}
@@ -0,0 +1,302 @@
+package gormutil
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"gorm.io/gorm/schema"
+
+	metav1 "github.com/xs0910/iam/pkg/component-base/meta/v1"
+	"github.com/xs0910/iam/pkg/component-base/validation/field"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// SortColumn is a column the rows are sorted by.
+type SortColumn struct {
+	Name string
+	Desc bool
+}
+
+// Keyset paginates lists by the keyset of the last row of the previous page, which is
+// passed in the opaque continue tokens of ListOptions and ListMeta. Unlike Offset, the
+// rows before the cursor are not scanned, and no row is skipped or repeated when rows
+// are inserted or deleted between the calls.
+type Keyset struct {
+	// Secret is the HMAC key signing the continue tokens, so the clients cannot forge them.
+	// It must not be empty, the continue tokens are neither encoded nor decoded otherwise.
+	Secret []byte
+	// Columns are the columns the pages are sorted by, they must not be NULL. The primary
+	// key is appended to break the ties, the pages are sorted by the primary key only if empty.
+	Columns []SortColumn
+}
+
+// NewKeyset returns a Keyset paginating lists sorted by columns, whose continue tokens
+// are signed with secret. It will panic when secret is empty.
+func NewKeyset(secret []byte, columns ...SortColumn) *Keyset {
+	if len(secret) == 0 {
+		panic("secret of keyset must not be empty")
+	}
+
+	return &Keyset{Secret: secret, Columns: columns}
+}
+
+// continueToken is the payload of a continue token.
+type continueToken struct {
+	// Order is the order of the list, the tokens of lists in other orders are rejected.
+	Order string `json:"o"`
+	// Values are the values of the sort columns of the last row, in the order of the columns.
+	Values []json.RawMessage `json:"v"`
+}
+
+// Scope validates opts.Continue, and returns a scope which sorts the rows and selects
+// the page after the continue token, or the first page if there is none. The scope selects
+// opts.Limit rows and one more, so SetContinue knows whether there are more rows, and
+// ignores the offset when opts.Continue is set.
+func (k *Keyset) Scope(opts *metav1.ListOptions) (Scope, field.ErrorList) {
+	var token *continueToken
+	if opts.Continue != "" {
+		var err error
+		if token, err = k.decode(opts.Continue); err != nil {
+			return nil, field.ErrorList{field.Invalid(field.NewPath("continue"), opts.Continue, err.Error())}
+		}
+		if token.Order != k.order() {
+			return nil, field.ErrorList{field.Invalid(field.NewPath("continue"), opts.Continue,
+				"continue token of a list in another order")}
+		}
+	}
+
+	return func(tx *gorm.DB) *gorm.DB {
+		columns, err := k.columns(tx.Statement)
+		if err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+
+		if token != nil {
+			if len(token.Values) != len(columns) {
+				_ = tx.AddError(errors.New("continue token of another schema"))
+				return tx
+			}
+
+			values := make([]interface{}, len(columns))
+			for i, column := range columns {
+				value := reflect.New(column.field.FieldType)
+				if err := json.Unmarshal(token.Values[i], value.Interface()); err != nil {
+					_ = tx.AddError(errors.Wrapf(err, "decode continue value of %s", column.Name))
+					return tx
+				}
+				values[i] = value.Elem().Interface()
+			}
+
+			tx = tx.Where(after(columns, values)).Offset(-1)
+		}
+
+		for _, column := range columns {
+			tx = tx.Order(clause.OrderByColumn{
+				Column: clause.Column{Table: clause.CurrentTable, Name: column.Name},
+				Desc:   column.Desc,
+			})
+		}
+
+		if opts.Limit != nil && *opts.Limit > 0 {
+			tx = tx.Limit(int(*opts.Limit) + 1)
+		}
+
+		return tx
+	}, nil
+}
+
+// SetContinue removes the extra row selected by the scope from list, a pointer to the
+// slice of rows found, and sets the continue token of the next page in meta if there are
+// more rows, or clears it.
+func (k *Keyset) SetContinue(db *gorm.DB, list interface{}, opts *metav1.ListOptions, meta metav1.ListInterface) error {
+	meta.SetContinue("")
+
+	rows := reflect.ValueOf(list)
+	if rows.Kind() != reflect.Ptr || rows.Elem().Kind() != reflect.Slice {
+		return errors.Errorf("list must be a pointer to a slice, got %T", list)
+	}
+	rows = rows.Elem()
+	if opts.Limit == nil || *opts.Limit <= 0 || int64(rows.Len()) <= *opts.Limit {
+		return nil
+	}
+	rows.Set(rows.Slice(0, int(*opts.Limit)))
+
+	stmt := &gorm.Statement{DB: db}
+	if err := stmt.Parse(list); err != nil {
+		return errors.Wrap(err, "parse schema of list")
+	}
+	columns, err := k.columns(stmt)
+	if err != nil {
+		return err
+	}
+
+	last := reflect.Indirect(rows.Index(rows.Len() - 1))
+	token := &continueToken{Order: k.order()}
+	for _, column := range columns {
+		value, _ := column.field.ValueOf(last)
+		data, err := json.Marshal(value)
+		if err != nil {
+			return errors.Wrapf(err, "encode continue value of %s", column.Name)
+		}
+		token.Values = append(token.Values, data)
+	}
+
+	encoded, err := k.encode(token)
+	if err != nil {
+		return err
+	}
+	meta.SetContinue(encoded)
+
+	return nil
+}
+
+// keysetColumn is a sort column with its field in the schema of the rows.
+type keysetColumn struct {
+	SortColumn
+	field *schema.Field
+}
+
+// columns returns the sort columns of the rows of stmt, the primary key included.
+func (k *Keyset) columns(stmt *gorm.Statement) ([]keysetColumn, error) {
+	if stmt.Schema == nil {
+		model := stmt.Model
+		if model == nil {
+			model = stmt.Dest
+		}
+		if err := stmt.Parse(model); err != nil {
+			return nil, errors.Wrap(err, "parse schema of rows")
+		}
+	}
+
+	primary := stmt.Schema.PrioritizedPrimaryField
+	if primary == nil {
+		return nil, errors.Errorf("%s has no primary key to paginate by", stmt.Schema.Name)
+	}
+
+	columns := make([]keysetColumn, 0, len(k.Columns)+1)
+	for _, c := range k.Columns {
+		f := stmt.Schema.LookUpField(c.Name)
+		if f == nil || f.DBName == "" {
+			return nil, errors.Errorf("%s has no column %s", stmt.Schema.Name, c.Name)
+		}
+		if f == primary {
+			continue
+		}
+		columns = append(columns, keysetColumn{SortColumn{Name: f.DBName, Desc: c.Desc}, f})
+	}
+
+	// the primary key is sorted like the last column.
+	desc := len(columns) > 0 && columns[len(columns)-1].Desc
+	for _, c := range k.Columns {
+		if stmt.Schema.LookUpField(c.Name) == primary {
+			desc = c.Desc
+		}
+	}
+
+	return append(columns, keysetColumn{SortColumn{Name: primary.DBName, Desc: desc}, primary}), nil
+}
+
+// after returns the condition of the rows after the row whose sort columns are values:
+// (c1 > v1) OR (c1 = v1 AND c2 > v2) OR ..., with < for the descending columns.
+func after(columns []keysetColumn, values []interface{}) clause.Expression {
+	exprs := make([]clause.Expression, 0, len(columns))
+	for i, c := range columns {
+		conds := make([]clause.Expression, 0, i+1)
+		for j := 0; j < i; j++ {
+			conds = append(conds, clause.Eq{Column: clause.Column{Table: clause.CurrentTable, Name: columns[j].Name}, Value: values[j]})
+		}
+
+		col := clause.Column{Table: clause.CurrentTable, Name: c.Name}
+		if c.Desc {
+			conds = append(conds, clause.Lt{Column: col, Value: values[i]})
+		} else {
+			conds = append(conds, clause.Gt{Column: col, Value: values[i]})
+		}
+
+		exprs = append(exprs, clause.And(conds...))
+	}
+
+	// a single OrConditions would be joined with the other conditions by OR.
+	if len(exprs) == 1 {
+		return exprs[0]
+	}
+
+	return clause.Or(exprs...)
+}
+
+// order returns the order of the pages, e.g. `name,-createdAt`.
+func (k *Keyset) order() string {
+	names := make([]string, 0, len(k.Columns))
+	for _, c := range k.Columns {
+		if c.Desc {
+			names = append(names, "-"+c.Name)
+		} else {
+			names = append(names, c.Name)
+		}
+	}
+
+	return strings.Join(names, ",")
+}
+
+// errEmptySecret is returned for the continue tokens of a Keyset without secret.
+var errEmptySecret = errors.New("secret of keyset is empty")
+
+// encode returns the signed continue token of t: the base64 payload and its HMAC-SHA256
+// signature joined by a dot.
+func (k *Keyset) encode(t *continueToken) (string, error) {
+	if len(k.Secret) == 0 {
+		return "", errEmptySecret
+	}
+
+	payload, err := json.Marshal(t)
+	if err != nil {
+		return "", errors.Wrap(err, "encode continue token")
+	}
+
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+
+	return encoded + "." + base64.RawURLEncoding.EncodeToString(k.sign(encoded)), nil
+}
+
+// decode verifies the signature of the continue token s and returns its payload.
+func (k *Keyset) decode(s string) (*continueToken, error) {
+	if len(k.Secret) == 0 {
+		return nil, errEmptySecret
+	}
+
+	parts := strings.Split(s, ".")
+	if len(parts) != 2 {
+		return nil, errors.New("malformed continue token")
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil || !hmac.Equal(signature, k.sign(parts[0])) {
+		return nil, errors.New("invalid continue token signature")
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return nil, errors.New("malformed continue token")
+	}
+
+	var t continueToken
+	if err := json.Unmarshal(payload, &t); err != nil {
+		return nil, errors.New("malformed continue token")
+	}
+
+	return &t, nil
+}
+
+func (k *Keyset) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, k.Secret)
+	mac.Write([]byte(payload))
+
+	return mac.Sum(nil)
+}
@@ -0,0 +1,181 @@
+package gormutil
+
+import (
+	"reflect"
+	"testing"
+
+	"gorm.io/gorm"
+
+	metav1 "github.com/xs0910/iam/pkg/component-base/meta/v1"
+)
+
+// listPages lists all the pages of the users by keyset, and returns the names of the pages.
+func listPages(t *testing.T, db *gorm.DB, keyset *Keyset, limit int64) [][]string {
+	opts := &metav1.ListOptions{Limit: &limit}
+
+	var pages [][]string
+	for {
+		scope, errs := keyset.Scope(opts)
+		if len(errs) > 0 {
+			t.Fatal(errs)
+		}
+
+		var users []testUser
+		if err := db.Scopes(scope).Find(&users).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		meta := &metav1.ListMeta{}
+		if err := keyset.SetContinue(db, &users, opts, meta); err != nil {
+			t.Fatal(err)
+		}
+
+		var names []string
+		for _, u := range users {
+			names = append(names, u.Name)
+		}
+		pages = append(pages, names)
+
+		if meta.Continue == "" || len(pages) > 5 {
+			return pages
+		}
+		opts.Continue = meta.Continue
+	}
+}
+
+func TestKeyset(t *testing.T) {
+	db := newTestDB(t)
+	secret := []byte("continue-secret")
+
+	tests := []struct {
+		keyset *Keyset
+		limit  int64
+		want   [][]string
+	}{
+		{NewKeyset(secret), 3, [][]string{{"colin", "lily", "tom"}, {"jack"}}},
+		{NewKeyset(secret), 2, [][]string{{"colin", "lily"}, {"tom", "jack"}}},
+		{NewKeyset(secret, SortColumn{Name: "name"}), 3, [][]string{{"colin", "jack", "lily"}, {"tom"}}},
+		{NewKeyset(secret, SortColumn{Name: "Name", Desc: true}), 1, [][]string{{"tom"}, {"lily"}, {"jack"}, {"colin"}}},
+		{NewKeyset(secret, SortColumn{Name: "labels"}, SortColumn{Name: "id", Desc: true}), 3, [][]string{
+			{"tom", "colin", "lily"}, {"jack"},
+		}},
+		{NewKeyset(secret), 0, [][]string{{"colin", "lily", "tom", "jack"}}},
+	}
+
+	for _, tt := range tests {
+		if got := listPages(t, db, tt.keyset, tt.limit); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s limit %d: got %v, want %v", tt.keyset.order(), tt.limit, got, tt.want)
+		}
+	}
+}
+
+func TestKeysetContinueAfterInsert(t *testing.T) {
+	db := newTestDB(t)
+	keyset := NewKeyset([]byte("continue-secret"), SortColumn{Name: "name"})
+
+	limit := int64(2)
+	opts := &metav1.ListOptions{Limit: &limit}
+	scope, _ := keyset.Scope(opts)
+
+	var users []testUser
+	if err := db.Scopes(scope).Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	meta := &metav1.ListMeta{}
+	if err := keyset.SetContinue(db, &users, opts, meta); err != nil {
+		t.Fatal(err)
+	}
+
+	// colin and jack are listed, inserting before them must not shift the next page.
+	if err := db.Create(&testUser{ID: 5, Name: "alice", Labels: "{}"}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// the offset is ignored with a continue token.
+	offset := int64(1)
+	opts.Continue, opts.Offset = meta.Continue, &offset
+	scope, _ = keyset.Scope(opts)
+	users = nil
+	if err := db.Offset(int(offset)).Scopes(scope).Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Name != "lily" || users[1].Name != "tom" {
+		t.Errorf("got %+v, want lily and tom", users)
+	}
+}
+
+func TestKeysetInvalidContinue(t *testing.T) {
+	db := newTestDB(t)
+	keyset := NewKeyset([]byte("continue-secret"), SortColumn{Name: "name"})
+
+	limit := int64(1)
+	opts := &metav1.ListOptions{Limit: &limit}
+	scope, _ := keyset.Scope(opts)
+
+	var users []testUser
+	if err := db.Scopes(scope).Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	meta := &metav1.ListMeta{}
+	if err := keyset.SetContinue(db, &users, opts, meta); err != nil || meta.Continue == "" {
+		t.Fatalf("SetContinue(): got (%q, %v)", meta.Continue, err)
+	}
+
+	forged, _ := keyset.encode(&continueToken{Order: "name", Values: nil})
+	tests := []struct {
+		name   string
+		token  string
+		keyset *Keyset
+	}{
+		{"truncated", meta.Continue[1:], keyset},
+		{"tampered signature", meta.Continue + "x", keyset},
+		{"malformed", "not-a-token", keyset},
+		{"extra part", meta.Continue + ".extra", keyset},
+		{"other secret", meta.Continue, NewKeyset([]byte("other-secret"), SortColumn{Name: "name"})},
+		{"other order", meta.Continue, NewKeyset(keyset.Secret, SortColumn{Name: "name", Desc: true})},
+		{"empty secret", meta.Continue, &Keyset{Columns: keyset.Columns}},
+	}
+	for _, tt := range tests {
+		_, errs := tt.keyset.Scope(&metav1.ListOptions{Limit: &limit, Continue: tt.token})
+		if len(errs) != 1 || errs[0].Field != "continue" {
+			t.Errorf("%s: got errors %v", tt.name, errs)
+		}
+	}
+
+	// a token signed with the secret but of another schema is rejected by the query.
+	scope, errs := keyset.Scope(&metav1.ListOptions{Limit: &limit, Continue: forged})
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if err := db.Scopes(scope).Find(&users).Error; err == nil {
+		t.Error("got no error for a token of another schema")
+	}
+}
+
+func TestKeysetEmptySecret(t *testing.T) {
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Error("NewKeyset(): got no panic for an empty secret")
+			}
+		}()
+
+		NewKeyset(nil)
+	}()
+
+	db := newTestDB(t)
+	keyset := &Keyset{Columns: []SortColumn{{Name: "name"}}}
+
+	limit := int64(1)
+	opts := &metav1.ListOptions{Limit: &limit}
+	scope, _ := keyset.Scope(opts)
+
+	var users []testUser
+	if err := db.Scopes(scope).Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	meta := &metav1.ListMeta{}
+	if err := keyset.SetContinue(db, &users, opts, meta); err == nil || meta.Continue != "" {
+		t.Errorf("SetContinue(): got (%q, %v), want an error", meta.Continue, err)
+	}
+}
@@ -0,0 +1,59 @@
+package gormutil
+
+import (
+	"gorm.io/gorm"
+
+	metav1 "github.com/xs0910/iam/pkg/component-base/meta/v1"
+	"github.com/xs0910/iam/pkg/component-base/validation/field"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// errDryRun rolls back the transaction of a dry run.
+var errDryRun = errors.New("dry run")
+
+// ValidateDryRun validates the dry-run directives in fldPath, metav1.DryRunAll is the
+// only one supported.
+func ValidateDryRun(fldPath *field.Path, dryRun []string) field.ErrorList {
+	var allErrs field.ErrorList
+	for i, directive := range dryRun {
+		if directive != metav1.DryRunAll {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Index(i), directive, []string{metav1.DryRunAll}))
+		}
+	}
+
+	return allErrs
+}
+
+// IsDryRun reports whether the dry-run directives request a dry run.
+func IsDryRun(dryRun []string) bool {
+	return len(dryRun) > 0
+}
+
+// Transaction runs write in a transaction of db, which is committed unless dryRun is
+// set, e.g. to CreateOptions.DryRun. The transaction of a dry run is always rolled back,
+// so write is processed by the database and the hooks of the models as usual, and the
+// objects written are set as they would have been persisted, e.g. the defaulted fields of
+// ObjectMeta. The directives are validated by ValidateDryRun, the field errors are
+// returned with the code ErrValidation.
+func Transaction(db *gorm.DB, dryRun []string, write func(tx *gorm.DB) error) error {
+	if errs := ValidateDryRun(field.NewPath("dryRun"), dryRun); len(errs) > 0 {
+		return errors.WithValidation(ErrValidation, errs.ToAggregate())
+	}
+
+	if !IsDryRun(dryRun) {
+		return db.Transaction(write)
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := write(tx); err != nil {
+			return err
+		}
+
+		return errDryRun
+	})
+	if errors.Is(err, errDryRun) {
+		return nil
+	}
+
+	return err
+}
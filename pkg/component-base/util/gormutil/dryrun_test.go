@@ -0,0 +1,122 @@
+package gormutil
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"github.com/xs0910/iam/pkg/component-base/core"
+	metav1 "github.com/xs0910/iam/pkg/component-base/meta/v1"
+	"github.com/xs0910/iam/pkg/component-base/validation/field"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+type testPolicy struct {
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Username string `gorm:"column:username"`
+}
+
+func (testPolicy) TableName() string { return "policy" }
+
+type discardLogger struct{}
+
+func (discardLogger) LogError(*core.LogEntry) {}
+
+func TestTransactionDryRun(t *testing.T) {
+	db := newTestDB(t)
+	if err := db.AutoMigrate(&testPolicy{}); err != nil {
+		t.Fatal(err)
+	}
+
+	count := func() int64 {
+		var n int64
+		if err := db.Model(&testPolicy{}).Count(&n).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		return n
+	}
+
+	policy := &testPolicy{
+		ObjectMeta: metav1.ObjectMeta{InstanceID: "policy-1", Name: "policy", Extend: metav1.Extend{"k": "v"}},
+		Username:   "colin",
+	}
+	err := Transaction(db, (&metav1.CreateOptions{DryRun: []string{metav1.DryRunAll}}).DryRun, func(tx *gorm.DB) error {
+		return tx.Create(policy).Error
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if policy.ID == 0 || policy.CreatedAt.IsZero() || policy.UpdatedAt.IsZero() || policy.ExtendShadow != `{"k":"v"}` {
+		t.Errorf("dry run: got %+v, want the defaulted fields set", policy.ObjectMeta)
+	}
+	if n := count(); n != 0 {
+		t.Errorf("dry run: got %d policies persisted", n)
+	}
+
+	// the write is persisted without dry run.
+	if err := Transaction(db, nil, func(tx *gorm.DB) error {
+		return tx.Create(&testPolicy{ObjectMeta: metav1.ObjectMeta{InstanceID: "policy-1", Name: "policy"}}).Error
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if n := count(); n != 1 {
+		t.Errorf("got %d policies persisted, want 1", n)
+	}
+
+	// the errors of the database are returned by dry runs too.
+	err = Transaction(db, []string{metav1.DryRunAll}, func(tx *gorm.DB) error {
+		return tx.Create(&testPolicy{ObjectMeta: metav1.ObjectMeta{InstanceID: "policy-1", Name: "duplicate"}}).Error
+	})
+	if err == nil {
+		t.Error("dry run: got no error for a duplicate instanceID")
+	}
+
+	errWrite := errors.New("write failed")
+	if err := Transaction(db, []string{metav1.DryRunAll}, func(tx *gorm.DB) error { return errWrite }); err != errWrite {
+		t.Errorf("dry run: got error %v, want %v", err, errWrite)
+	}
+}
+
+func TestValidateDryRun(t *testing.T) {
+	fldPath := field.NewPath("dryRun")
+
+	if errs := ValidateDryRun(fldPath, []string{metav1.DryRunAll}); len(errs) != 0 {
+		t.Errorf("All: got errors %v", errs)
+	}
+	if errs := ValidateDryRun(fldPath, nil); len(errs) != 0 {
+		t.Errorf("nil: got errors %v", errs)
+	}
+
+	errs := ValidateDryRun(fldPath, []string{metav1.DryRunAll, "Admission"})
+	if len(errs) != 1 || errs[0].Type != field.ErrorTypeNotSupported || errs[0].Field != "dryRun[1]" {
+		t.Errorf("got errors %v", errs)
+	}
+
+	called := false
+	err := Transaction(nil, []string{"all"}, func(tx *gorm.DB) error {
+		called = true
+		return nil
+	})
+	if err == nil || called {
+		t.Errorf("unknown directive: got error %v, write called: %v", err, called)
+	}
+
+	// the invalid directives are bad requests.
+	gin.SetMode(gin.TestMode)
+	core.SetLogger(discardLogger{})
+	t.Cleanup(func() { core.SetLogger(nil) })
+
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/policies?dryRun=all", nil)
+	core.WriteResponse(c, err, nil)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "dryRun[0]") {
+		t.Errorf("unknown directive: got response %d %s, want %d", w.Code, w.Body.String(), http.StatusBadRequest)
+	}
+}
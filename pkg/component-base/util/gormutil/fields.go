@@ -0,0 +1,238 @@
+package gormutil
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/xs0910/iam/pkg/component-base/fields"
+	metav1 "github.com/xs0910/iam/pkg/component-base/meta/v1"
+	"github.com/xs0910/iam/pkg/component-base/selection"
+	"github.com/xs0910/iam/pkg/component-base/validation/field"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// FieldMapping maps the selectable fields of a resource to their columns.
+type FieldMapping map[string]string
+
+// ObjectMetaFields are the selectable fields of ObjectMeta, which are JSON paths of the objects.
+var ObjectMetaFields = FieldMapping{
+	"metadata.name":       "name",
+	"metadata.instanceID": "instanceID",
+}
+
+// With returns a copy of m with the fields of other.
+func (m FieldMapping) With(other FieldMapping) FieldMapping {
+	merged := make(FieldMapping, len(m)+len(other))
+	for f, column := range m {
+		merged[f] = column
+	}
+	for f, column := range other {
+		merged[f] = column
+	}
+
+	return merged
+}
+
+// Fields returns the sorted selectable fields.
+func (m FieldMapping) Fields() []string {
+	list := make([]string, 0, len(m))
+	for f := range m {
+		list = append(list, f)
+	}
+	sort.Strings(list)
+
+	return list
+}
+
+// Selector validates the field selector in fldPath against m, and returns a scope which
+// selects the rows matching it. The fields are compared with = and !=.
+func (m FieldMapping) Selector(fldPath *field.Path, selector string) (Scope, field.ErrorList) {
+	parsed, err := fields.ParseSelector(selector)
+	if err != nil {
+		return nil, field.ErrorList{field.Invalid(fldPath, selector, err.Error())}
+	}
+
+	var allErrs field.ErrorList
+	exprs := []clause.Expression{}
+	for _, r := range parsed.Requirements() {
+		column, ok := m[r.Field]
+		if !ok {
+			allErrs = append(allErrs, field.NotSupported(fldPath, r.Field, m.Fields()))
+			continue
+		}
+
+		col := clause.Column{Table: clause.CurrentTable, Name: column}
+		switch r.Operator {
+		case selection.Equals, selection.DoubleEquals:
+			exprs = append(exprs, clause.Eq{Column: col, Value: r.Value})
+		case selection.NotEquals:
+			exprs = append(exprs, clause.Neq{Column: col, Value: r.Value})
+		default:
+			allErrs = append(allErrs, field.Invalid(fldPath, selector,
+				fmt.Sprintf("unsupported operator %q of %s", r.Operator, r.Field)))
+		}
+	}
+	if len(allErrs) > 0 {
+		return nil, allErrs
+	}
+
+	if len(exprs) == 0 {
+		return noop, nil
+	}
+
+	return func(tx *gorm.DB) *gorm.DB {
+		return tx.Where(clause.And(exprs...))
+	}, nil
+}
+
+// FieldRegistry registers the fields of the resources which can be selected and projected
+// in the list calls, and those of them which can be sorted by.
+type FieldRegistry struct {
+	lock      sync.RWMutex
+	resources map[string]FieldMapping
+	sortable  map[string]FieldMapping
+}
+
+// DefaultFieldRegistry is the FieldRegistry used by the functions of this package.
+var DefaultFieldRegistry = NewFieldRegistry()
+
+// NewFieldRegistry returns an empty FieldRegistry.
+func NewFieldRegistry() *FieldRegistry {
+	return &FieldRegistry{resources: map[string]FieldMapping{}, sortable: map[string]FieldMapping{}}
+}
+
+// Register registers the selectable fields of resource, it panics if resource is already registered.
+func (r *FieldRegistry) Register(resource string, mapping FieldMapping) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	if _, ok := r.resources[resource]; ok {
+		panic(fmt.Sprintf("fields of resource %s already registered", resource))
+	}
+
+	r.resources[resource] = mapping
+}
+
+// RegisterSortable registers the fields of resource which can be sorted by, usually the
+// indexed ones. They must be registered fields of resource, it panics otherwise or if the
+// sortable fields of resource are already registered.
+func (r *FieldRegistry) RegisterSortable(resource string, fields ...string) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	mapping, ok := r.resources[resource]
+	if !ok {
+		panic(fmt.Sprintf("fields of resource %s not registered", resource))
+	}
+	if _, ok := r.sortable[resource]; ok {
+		panic(fmt.Sprintf("sortable fields of resource %s already registered", resource))
+	}
+
+	sortable := make(FieldMapping, len(fields))
+	for _, f := range fields {
+		column, ok := mapping[f]
+		if !ok {
+			panic(fmt.Sprintf("field %s of resource %s not registered", f, resource))
+		}
+		sortable[f] = column
+	}
+
+	r.sortable[resource] = sortable
+}
+
+// Fields returns the selectable fields of resource.
+func (r *FieldRegistry) Fields(resource string) (FieldMapping, bool) {
+	r.lock.RLock()
+	defer r.lock.RUnlock()
+
+	mapping, ok := r.resources[resource]
+
+	return mapping, ok
+}
+
+// FieldSelector validates opts.FieldSelector against the selectable fields of resource,
+// and returns a scope which selects the rows of resource matching it.
+func (r *FieldRegistry) FieldSelector(resource string, opts *metav1.ListOptions) (Scope, field.ErrorList) {
+	fldPath := field.NewPath("fieldSelector")
+
+	mapping, errs := r.mapping(fldPath, resource)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return mapping.Selector(fldPath, opts.FieldSelector)
+}
+
+// SortBy validates opts.SortBy against the sortable fields of resource, and returns the
+// columns to sort by. No field is sortable unless registered by RegisterSortable.
+func (r *FieldRegistry) SortBy(resource string, opts *metav1.ListOptions) ([]SortColumn, field.ErrorList) {
+	fldPath := field.NewPath("sortBy")
+
+	if _, errs := r.mapping(fldPath, resource); len(errs) > 0 {
+		return nil, errs
+	}
+
+	r.lock.RLock()
+	sortable := r.sortable[resource]
+	r.lock.RUnlock()
+
+	return sortable.SortBy(fldPath, opts.SortBy)
+}
+
+// Projection validates opts.Fields against the fields of resource, and returns their projection.
+func (r *FieldRegistry) Projection(resource string, opts *metav1.ListOptions) (*Projection, field.ErrorList) {
+	fldPath := field.NewPath("fields")
+
+	mapping, errs := r.mapping(fldPath, resource)
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	return mapping.Projection(fldPath, opts.Fields)
+}
+
+// mapping returns the fields of resource, or an internal error in fldPath if it's not registered.
+func (r *FieldRegistry) mapping(fldPath *field.Path, resource string) (FieldMapping, field.ErrorList) {
+	mapping, ok := r.Fields(resource)
+	if !ok {
+		return nil, field.ErrorList{field.InternalError(fldPath, errors.Errorf("fields of resource %s not registered", resource))}
+	}
+
+	return mapping, nil
+}
+
+// RegisterFields registers the selectable fields of resource in DefaultFieldRegistry.
+func RegisterFields(resource string, mapping FieldMapping) {
+	DefaultFieldRegistry.Register(resource, mapping)
+}
+
+// RegisterSortable registers the sortable fields of resource in DefaultFieldRegistry.
+func RegisterSortable(resource string, fields ...string) {
+	DefaultFieldRegistry.RegisterSortable(resource, fields...)
+}
+
+// FieldSelector validates opts.FieldSelector against the fields of resource registered
+// in DefaultFieldRegistry, and returns a scope which selects the rows matching it.
+func FieldSelector(resource string, opts *metav1.ListOptions) (Scope, field.ErrorList) {
+	return DefaultFieldRegistry.FieldSelector(resource, opts)
+}
+
+func noop(tx *gorm.DB) *gorm.DB {
+	return tx
+}
+
+// SortBy validates opts.SortBy against the sortable fields of resource registered in
+// DefaultFieldRegistry, and returns the columns to sort by.
+func SortBy(resource string, opts *metav1.ListOptions) ([]SortColumn, field.ErrorList) {
+	return DefaultFieldRegistry.SortBy(resource, opts)
+}
+
+// Fields validates opts.Fields against the fields of resource registered in
+// DefaultFieldRegistry, and returns their projection.
+func Fields(resource string, opts *metav1.ListOptions) (*Projection, field.ErrorList) {
+	return DefaultFieldRegistry.Projection(resource, opts)
+}
@@ -0,0 +1,65 @@
+package gormutil
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "github.com/xs0910/iam/pkg/component-base/meta/v1"
+	"github.com/xs0910/iam/pkg/component-base/validation/field"
+)
+
+func TestFieldSelector(t *testing.T) {
+	db := newTestDB(t)
+
+	registry := NewFieldRegistry()
+	registry.Register("users", FieldMapping{"name": "name", "metadata.id": "id"})
+
+	tests := []struct {
+		selector  string
+		want      []string
+		wantError field.ErrorType
+	}{
+		{"", []string{"colin", "lily", "tom", "jack"}, ""},
+		{"name=lily", []string{"lily"}, ""},
+		{"name==lily", []string{"lily"}, ""},
+		{"name!=lily,metadata.id!=1", []string{"tom", "jack"}, ""},
+		{`name=it's`, []string{}, ""},
+		{"status=active", nil, field.ErrorTypeNotSupported},
+		{"name", nil, field.ErrorTypeInvalid},
+	}
+
+	for _, tt := range tests {
+		scope, errs := registry.FieldSelector("users", &metav1.ListOptions{FieldSelector: tt.selector})
+		if tt.wantError != "" {
+			if len(errs) != 1 || errs[0].Type != tt.wantError || errs[0].Field != "fieldSelector" {
+				t.Errorf("%q: got errors %v, want %s", tt.selector, errs, tt.wantError)
+			}
+			continue
+		}
+		if len(errs) > 0 {
+			t.Errorf("%q: got errors %v", tt.selector, errs)
+			continue
+		}
+
+		got, err := findUsers(db, scope)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got (%v, %v), want %v", tt.selector, got, err, tt.want)
+		}
+	}
+
+	if _, errs := registry.FieldSelector("groups", &metav1.ListOptions{}); len(errs) != 1 {
+		t.Errorf("unregistered resource: got errors %v", errs)
+	}
+}
+
+func TestFieldMappingWith(t *testing.T) {
+	mapping := ObjectMetaFields.With(FieldMapping{"status": "status"})
+
+	want := []string{"metadata.instanceID", "metadata.name", "status"}
+	if got := mapping.Fields(); !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if _, ok := ObjectMetaFields["status"]; ok {
+		t.Error("ObjectMetaFields modified")
+	}
+}
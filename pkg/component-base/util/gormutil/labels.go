@@ -0,0 +1,169 @@
+// Package gormutil translates the list options of the REST calls into gorm query scopes.
+package gormutil
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/xs0910/iam/pkg/component-base/labels"
+	"github.com/xs0910/iam/pkg/component-base/selection"
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+// Scope is a gorm query scope, applied with (*gorm.DB).Scopes.
+type Scope func(*gorm.DB) *gorm.DB
+
+// LabelSelector returns a scope which selects the rows whose labels match selector. The
+// labels are stored as a JSON object of strings in column, e.g. `{"app": "iam"}`.
+// The keys and values of the requirements are bound as parameters, the dialects supported
+// are sqlite, mysql and postgres. The operators gt and lt are not supported.
+func LabelSelector(column string, selector labels.Selector) Scope {
+	return func(tx *gorm.DB) *gorm.DB {
+		return applyLabelSelector(tx, selector, func(r *labels.Requirement) (clause.Expression, error) {
+			value, err := jsonValue(tx.Dialector.Name(), column, r.Key())
+			if err != nil {
+				return nil, err
+			}
+
+			return labelCondition(r, func(values []string) clause.Expression {
+				return clause.Expr{SQL: "? IN ?", Vars: []interface{}{value, values}}
+			}, func(values []string) clause.Expression {
+				if values == nil {
+					return clause.Expr{SQL: "(? IS NOT NULL)", Vars: []interface{}{value}}
+				}
+
+				return clause.Expr{SQL: "(? IS NOT NULL AND ? IN ?)", Vars: []interface{}{value, value, values}}
+			})
+		})
+	}
+}
+
+// LabelsTable describes a normalized labels table, which stores a row per label of the
+// rows of the owner table.
+type LabelsTable struct {
+	// Name is the name of the labels table.
+	Name string
+	// ForeignKey is the column of the labels table referencing the owner row.
+	ForeignKey string
+	// References is the column of the owner table ForeignKey references, it's `id` by default.
+	References string
+	// KeyColumn is the column of the label keys, it's `key` by default.
+	KeyColumn string
+	// ValueColumn is the column of the label values, it's `value` by default.
+	ValueColumn string
+}
+
+// LabelsTableSelector returns a scope which selects the rows of the owner table whose
+// labels in table match selector. The operators gt and lt are not supported.
+func LabelsTableSelector(table LabelsTable, selector labels.Selector) Scope {
+	return func(tx *gorm.DB) *gorm.DB {
+		return applyLabelSelector(tx, selector, func(r *labels.Requirement) (clause.Expression, error) {
+			exists := func(values []string) clause.Expression {
+				expr := clause.Expr{
+					SQL: "EXISTS (SELECT 1 FROM ? WHERE ? = ? AND ? = ?",
+					Vars: []interface{}{
+						clause.Table{Name: table.Name},
+						clause.Column{Table: table.Name, Name: table.ForeignKey},
+						clause.Column{Table: clause.CurrentTable, Name: orDefault(table.References, "id")},
+						clause.Column{Table: table.Name, Name: orDefault(table.KeyColumn, "key")},
+						r.Key(),
+					},
+				}
+				if values != nil {
+					expr.SQL += " AND ? IN ?"
+					expr.Vars = append(expr.Vars,
+						clause.Column{Table: table.Name, Name: orDefault(table.ValueColumn, "value")}, values)
+				}
+				expr.SQL += ")"
+
+				return expr
+			}
+
+			return labelCondition(r, exists, exists)
+		})
+	}
+}
+
+// applyLabelSelector adds the conditions of the requirements of selector built by condition to tx.
+func applyLabelSelector(
+	tx *gorm.DB,
+	selector labels.Selector,
+	condition func(r *labels.Requirement) (clause.Expression, error),
+) *gorm.DB {
+	if selector == nil || selector.Empty() {
+		return tx
+	}
+
+	requirements, selectable := selector.Requirements()
+	if !selectable {
+		return tx.Where("1 = 0")
+	}
+
+	exprs := make([]clause.Expression, 0, len(requirements))
+	for i := range requirements {
+		expr, err := condition(&requirements[i])
+		if err != nil {
+			_ = tx.AddError(err)
+			return tx
+		}
+		exprs = append(exprs, expr)
+	}
+
+	return tx.Where(clause.And(exprs...))
+}
+
+// labelCondition returns the condition of r. in returns the condition of a label whose value
+// is one of values, exists returns the condition of an existing label whose value is one of
+// values, or whatever its value if values is nil. The label of a negative requirement may be
+// missing, like in (*labels.Requirement).Matches.
+func labelCondition(
+	r *labels.Requirement,
+	in func(values []string) clause.Expression,
+	exists func(values []string) clause.Expression,
+) (clause.Expression, error) {
+	switch r.Operator() {
+	case selection.Equals, selection.DoubleEquals, selection.In:
+		return in(r.Values().List()), nil
+	case selection.NotEquals, selection.NotIn:
+		return clause.Not(exists(r.Values().List())), nil
+	case selection.Exists:
+		return exists(nil), nil
+	case selection.DoesNotExist:
+		return clause.Not(exists(nil)), nil
+	default:
+		return nil, errors.Errorf("unsupported label selector operator %q of %s", r.Operator(), r.Key())
+	}
+}
+
+// jsonValue returns the expression of the string value of key in the JSON object of column,
+// which is NULL if key is missing.
+func jsonValue(dialect, column, key string) (clause.Expression, error) {
+	col := clause.Column{Table: clause.CurrentTable, Name: column}
+
+	switch dialect {
+	case "sqlite":
+		return clause.Expr{SQL: "json_extract(?, ?)", Vars: []interface{}{col, jsonPath(key)}}, nil
+	case "mysql":
+		return clause.Expr{SQL: "JSON_UNQUOTE(JSON_EXTRACT(?, ?))", Vars: []interface{}{col, jsonPath(key)}}, nil
+	case "postgres":
+		return clause.Expr{SQL: "(? ->> ?)", Vars: []interface{}{col, key}}, nil
+	default:
+		return nil, errors.Errorf("label selector is not supported by dialect %s", dialect)
+	}
+}
+
+// jsonPath returns the JSON path of the member key, which is quoted since label keys may
+// contain dots and slashes.
+func jsonPath(key string) string {
+	return `$."` + strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(key) + `"`
+}
+
+func orDefault(s, def string) string {
+	if s == "" {
+		return def
+	}
+
+	return s
+}
@@ -0,0 +1,160 @@
+package gormutil
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+
+	"github.com/xs0910/iam/pkg/component-base/labels"
+)
+
+type testUser struct {
+	ID     uint64 `gorm:"primary_key;column:id"`
+	Name   string `gorm:"column:name"`
+	Labels string `gorm:"column:labels"`
+}
+
+func (testUser) TableName() string { return "user" }
+
+type testUserLabel struct {
+	UserID uint64 `gorm:"column:userID"`
+	Key    string `gorm:"column:key"`
+	Value  string `gorm:"column:value"`
+}
+
+func (testUserLabel) TableName() string { return "user_label" }
+
+var testUserLabels = []struct {
+	name   string
+	labels map[string]string
+}{
+	{"colin", map[string]string{"app": "iam", "tier": "backend", "example.com/team": "a.b"}},
+	{"lily", map[string]string{"app": "iam", "tier": "frontend"}},
+	{"tom", map[string]string{"app": "blog"}},
+	{"jack", nil},
+}
+
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open("file::memory:"), &gorm.Config{Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.AutoMigrate(&testUser{}, &testUserLabel{}); err != nil {
+		t.Fatal(err)
+	}
+
+	for i, u := range testUserLabels {
+		data, _ := json.Marshal(u.labels)
+		user := &testUser{ID: uint64(i + 1), Name: u.name, Labels: string(data)}
+		if u.labels == nil {
+			user.Labels = "{}"
+		}
+		if err := db.Create(user).Error; err != nil {
+			t.Fatal(err)
+		}
+
+		for k, v := range u.labels {
+			if err := db.Create(&testUserLabel{UserID: user.ID, Key: k, Value: v}).Error; err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+
+	return db
+}
+
+func findUsers(db *gorm.DB, scope Scope) ([]string, error) {
+	var users []testUser
+	if err := db.Scopes(scope).Order("id").Find(&users).Error; err != nil {
+		return nil, err
+	}
+
+	names := []string{}
+	for _, u := range users {
+		names = append(names, u.Name)
+	}
+
+	return names, nil
+}
+
+func TestLabelSelector(t *testing.T) {
+	db := newTestDB(t)
+	table := LabelsTable{Name: "user_label", ForeignKey: "userID"}
+
+	tests := []struct {
+		selector string
+		want     []string
+	}{
+		{"", []string{"colin", "lily", "tom", "jack"}},
+		{"app=iam", []string{"colin", "lily"}},
+		{"app==blog", []string{"tom"}},
+		{"app!=iam", []string{"tom", "jack"}},
+		{"tier in (backend,frontend)", []string{"colin", "lily"}},
+		{"tier notin (backend)", []string{"lily", "tom", "jack"}},
+		{"tier", []string{"colin", "lily"}},
+		{"!tier", []string{"tom", "jack"}},
+		{"app=iam,tier!=backend", []string{"lily"}},
+		{"example.com/team=a.b", []string{"colin"}},
+		{"app=nothing", []string{}},
+	}
+
+	for _, tt := range tests {
+		selector, err := labels.Parse(tt.selector)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		got, err := findUsers(db, LabelSelector("labels", selector))
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LabelSelector(%q): got (%v, %v), want %v", tt.selector, got, err, tt.want)
+		}
+
+		got, err = findUsers(db, LabelsTableSelector(table, selector))
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("LabelsTableSelector(%q): got (%v, %v), want %v", tt.selector, got, err, tt.want)
+		}
+	}
+}
+
+func TestLabelSelectorEscaping(t *testing.T) {
+	db := newTestDB(t)
+
+	selector, err := labels.Parse("example.com/team in (a.b,c)")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var users []testUser
+	stmt := db.Session(&gorm.Session{DryRun: true}).Scopes(LabelSelector("labels", selector)).Find(&users).Statement
+
+	wantSQL := "SELECT * FROM `user` WHERE json_extract(`user`.`labels`, ?) IN (?,?)"
+	wantVars := []interface{}{`$."example.com/team"`, "a.b", "c"}
+	if got := stmt.SQL.String(); got != wantSQL || !reflect.DeepEqual(stmt.Vars, wantVars) {
+		t.Errorf("got %s %v, want %s %v", got, stmt.Vars, wantSQL, wantVars)
+	}
+
+	if got, want := jsonPath(`a"b\c`), `$."a\"b\\c"`; got != want {
+		t.Errorf("jsonPath(): got %s, want %s", got, want)
+	}
+}
+
+func TestLabelSelectorUnselectable(t *testing.T) {
+	db := newTestDB(t)
+
+	if got, err := findUsers(db, LabelSelector("labels", labels.Nothing())); err != nil || len(got) != 0 {
+		t.Errorf("Nothing(): got (%v, %v)", got, err)
+	}
+
+	selector, err := labels.Parse("replicas>1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := findUsers(db, LabelSelector("labels", selector)); err == nil {
+		t.Error("gt: got no error")
+	}
+}
@@ -0,0 +1,138 @@
+package gormutil
+
+import (
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/xs0910/iam/pkg/component-base/util/sets"
+	"github.com/xs0910/iam/pkg/component-base/validation/field"
+)
+
+// RequiredObjectMetaColumns are the columns selected whatever the projection of the
+// objects embedding ObjectMeta, which needs them after find.
+var RequiredObjectMetaColumns = []string{"id", "extendShadow"}
+
+// SortBy validates sortBy in fldPath against m, a comma-separated list of fields prefixed
+// by `-` to sort in descending order, and returns the columns to sort by with Order, or
+// to paginate by with NewKeyset.
+func (m FieldMapping) SortBy(fldPath *field.Path, sortBy string) ([]SortColumn, field.ErrorList) {
+	var (
+		allErrs field.ErrorList
+		columns []SortColumn
+	)
+
+	seen := sets.NewString()
+	for i, f := range splitList(sortBy) {
+		desc := strings.HasPrefix(f, "-")
+		f = strings.TrimPrefix(f, "-")
+
+		column, ok := m[f]
+		switch {
+		case !ok:
+			allErrs = append(allErrs, field.NotSupported(fldPath.Index(i), f, m.Fields()))
+		case seen.Has(column):
+			allErrs = append(allErrs, field.Duplicate(fldPath.Index(i), f))
+		default:
+			seen.Insert(column)
+			columns = append(columns, SortColumn{Name: column, Desc: desc})
+		}
+	}
+	if len(allErrs) > 0 {
+		return nil, allErrs
+	}
+
+	return columns, nil
+}
+
+// Order returns a scope which sorts the rows by columns.
+func Order(columns []SortColumn) Scope {
+	return func(tx *gorm.DB) *gorm.DB {
+		for _, c := range columns {
+			tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Table: clause.CurrentTable, Name: c.Name}, Desc: c.Desc})
+		}
+
+		return tx
+	}
+}
+
+// Projection is a sparse fieldset, the fields of the objects to be returned.
+type Projection struct {
+	// Fields are the requested fields, they are JSON paths of the objects like `metadata.name`.
+	Fields []string
+	// Columns are the columns of Fields.
+	Columns []string
+}
+
+// Projection validates the comma-separated fields in fldPath against m, and returns their
+// projection, nil if fields is empty.
+func (m FieldMapping) Projection(fldPath *field.Path, fields string) (*Projection, field.ErrorList) {
+	list := splitList(fields)
+	if len(list) == 0 {
+		return nil, nil
+	}
+
+	var allErrs field.ErrorList
+	p := &Projection{}
+	columns := sets.NewString()
+	for i, f := range list {
+		column, ok := m[f]
+		if !ok {
+			allErrs = append(allErrs, field.NotSupported(fldPath.Index(i), f, m.Fields()))
+			continue
+		}
+
+		p.Fields = append(p.Fields, f)
+		if !columns.Has(column) {
+			columns.Insert(column)
+			p.Columns = append(p.Columns, column)
+		}
+	}
+	if len(allErrs) > 0 {
+		return nil, allErrs
+	}
+
+	return p, nil
+}
+
+// Scope returns a scope which selects the columns of the projection and the required
+// columns, e.g. RequiredObjectMetaColumns. All the columns are selected if p is nil.
+func (p *Projection) Scope(required ...string) Scope {
+	return func(tx *gorm.DB) *gorm.DB {
+		if p == nil {
+			return tx
+		}
+
+		columns := append([]string{}, required...)
+		seen := sets.NewString(required...)
+		for _, c := range p.Columns {
+			if !seen.Has(c) {
+				columns = append(columns, c)
+			}
+		}
+
+		return tx.Select(columns)
+	}
+}
+
+// FieldNames returns the requested fields, nil if p is nil.
+func (p *Projection) FieldNames() []string {
+	if p == nil {
+		return nil
+	}
+
+	return p.Fields
+}
+
+// splitList splits the comma-separated list s.
+func splitList(s string) []string {
+	var list []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+
+	return list
+}
@@ -0,0 +1,107 @@
+package gormutil
+
+import (
+	"reflect"
+	"testing"
+
+	metav1 "github.com/xs0910/iam/pkg/component-base/meta/v1"
+	"github.com/xs0910/iam/pkg/component-base/validation/field"
+)
+
+func TestSortBy(t *testing.T) {
+	db := newTestDB(t)
+
+	registry := NewFieldRegistry()
+	registry.Register("users", FieldMapping{"name": "name", "labels": "labels", "metadata.id": "id", "status": "status"})
+	registry.RegisterSortable("users", "name", "metadata.id")
+
+	tests := []struct {
+		sortBy    string
+		want      []string
+		wantError field.ErrorType
+	}{
+		{"", []string{"colin", "lily", "tom", "jack"}, ""},
+		{"name", []string{"colin", "jack", "lily", "tom"}, ""},
+		{"-name", []string{"tom", "lily", "jack", "colin"}, ""},
+		{" name , -metadata.id", []string{"colin", "jack", "lily", "tom"}, ""},
+		{"-metadata.id", []string{"jack", "tom", "lily", "colin"}, ""},
+		{"labels", nil, field.ErrorTypeNotSupported},
+		{"status", nil, field.ErrorTypeNotSupported},
+		{"name,-name", nil, field.ErrorTypeDuplicate},
+	}
+
+	for _, tt := range tests {
+		columns, errs := registry.SortBy("users", &metav1.ListOptions{SortBy: tt.sortBy})
+		if tt.wantError != "" {
+			if len(errs) != 1 || errs[0].Type != tt.wantError {
+				t.Errorf("%q: got errors %v, want %s", tt.sortBy, errs, tt.wantError)
+			}
+			continue
+		}
+		if len(errs) > 0 {
+			t.Errorf("%q: got errors %v", tt.sortBy, errs)
+			continue
+		}
+
+		var users []testUser
+		err := db.Scopes(Order(columns)).Find(&users).Error
+
+		got := []string{}
+		for _, u := range users {
+			got = append(got, u.Name)
+		}
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got (%v, %v), want %v", tt.sortBy, got, err, tt.want)
+		}
+	}
+
+	// the fields are not sortable unless registered.
+	registry.Register("groups", FieldMapping{"name": "name"})
+	if _, errs := registry.SortBy("groups", &metav1.ListOptions{SortBy: "name"}); len(errs) != 1 || errs[0].Type != field.ErrorTypeNotSupported {
+		t.Errorf("no sortable fields: got errors %v", errs)
+	}
+	if _, errs := registry.SortBy("policies", &metav1.ListOptions{}); len(errs) != 1 || errs[0].Type != field.ErrorTypeInternal {
+		t.Errorf("unregistered resource: got errors %v", errs)
+	}
+
+	defer func() {
+		if r := recover(); r == nil {
+			t.Error("RegisterSortable(): got no panic for an unregistered field")
+		}
+	}()
+	registry.RegisterSortable("groups", "labels")
+}
+
+func TestProjection(t *testing.T) {
+	db := newTestDB(t)
+
+	mapping := FieldMapping{"metadata.name": "name", "name": "name", "labels": "labels"}
+
+	p, errs := mapping.Projection(field.NewPath("fields"), "metadata.name,name")
+	if len(errs) > 0 {
+		t.Fatal(errs)
+	}
+	if !reflect.DeepEqual(p.FieldNames(), []string{"metadata.name", "name"}) || !reflect.DeepEqual(p.Columns, []string{"name"}) {
+		t.Errorf("got projection %+v", p)
+	}
+
+	var users []testUser
+	if err := db.Scopes(p.Scope("id")).Order("id").Find(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 4 || users[0].ID != 1 || users[0].Name != "colin" || users[0].Labels != "" {
+		t.Errorf("got %+v", users[0])
+	}
+
+	if _, errs := mapping.Projection(field.NewPath("fields"), "name,password"); len(errs) != 1 || errs[0].Field != "fields[1]" {
+		t.Errorf("got errors %v", errs)
+	}
+
+	p, errs = mapping.Projection(field.NewPath("fields"), "")
+	if p != nil || len(errs) > 0 || p.FieldNames() != nil {
+		t.Errorf("got (%+v, %v) for no fields", p, errs)
+	}
+	if err := db.Scopes(p.Scope(RequiredObjectMetaColumns...)).Find(&users).Error; err != nil || users[0].Labels == "" {
+		t.Errorf("got (%+v, %v) for no fields", users[0], err)
+	}
+}
@@ -6,7 +6,6 @@ import (
 	"net"
 	"regexp"
 	"strings"
-	"unicode"
 )
 
 const (
@@ -31,9 +30,6 @@ const (
 
 	percentFmt    string = "[0-9]+%"
 	percentErrMsg string = "a valid percent string must be a numeric string followed by an ending '%'"
-
-	minPassLength = 8
-	maxPassLength = 16
 )
 
 var qualifiedNameRegexp = regexp.MustCompile("^" + qualifiedNameFmt + "$")
@@ -207,62 +203,7 @@ func IsValidIPv6Address(fldPath *field.Path, value string) field.ErrorList {
 	return allErrors
 }
 
-// IsValidPassword validate password.
+// IsValidPassword validate password against the default password policy, see NewPasswordPolicy.
 func IsValidPassword(password string) error {
-	var hasUpper bool
-	var hasLower bool
-	var hasNumber bool
-	var hasSpecial bool
-	var passLen int
-	var errorString string
-
-	for _, ch := range password {
-		switch {
-		case unicode.IsNumber(ch):
-			hasNumber = true
-			passLen++
-		case unicode.IsUpper(ch):
-			hasUpper = true
-			passLen++
-		case unicode.IsLower(ch):
-			hasLower = true
-			passLen++
-		case unicode.IsPunct(ch) || unicode.IsSymbol(ch):
-			hasSpecial = true
-			passLen++
-		case ch == ' ':
-			passLen++
-		}
-	}
-
-	appendError := func(err string) {
-		if len(strings.TrimSpace(errorString)) != 0 {
-			errorString += ", " + err
-		} else {
-			errorString = err
-		}
-	}
-	if !hasLower {
-		appendError("lowercase letter missing")
-	}
-	if !hasUpper {
-		appendError("uppercase letter missing")
-	}
-	if !hasNumber {
-		appendError("at least one numeric character required")
-	}
-	if !hasSpecial {
-		appendError("special character missing")
-	}
-	if !(minPassLength <= passLen && passLen > maxPassLength) {
-		appendError(
-			fmt.Sprintf("password length must be between %d to %d characters long", minPassLength, maxPassLength),
-		)
-	}
-
-	if len(errorString) != 0 {
-		return fmt.Errorf(errorString)
-	}
-
-	return nil
+	return NewPasswordPolicy().Validate(field.NewPath("password"), password, nil, nil).ToAggregate()
 }
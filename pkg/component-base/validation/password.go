@@ -0,0 +1,169 @@
+package validation
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/xs0910/iam/pkg/component-base/util/sets"
+	"github.com/xs0910/iam/pkg/component-base/validation/field"
+)
+
+// redactedPassword is reported as the bad value of password errors, passwords are never echoed.
+const redactedPassword = "******"
+
+// PasswordPolicy describes the rules a password must follow.
+type PasswordPolicy struct {
+	// MinLength and MaxLength bound the number of characters of the password,
+	// a zero MaxLength means no limit.
+	MinLength int
+	MaxLength int
+
+	// RequireUpper, RequireLower, RequireNumber and RequireSpecial require the password
+	// to contain at least one character of the class.
+	RequireUpper   bool
+	RequireLower   bool
+	RequireNumber  bool
+	RequireSpecial bool
+
+	// DisallowedSubstrings are substrings the password must not contain, compared
+	// case-insensitively, e.g. the product name.
+	DisallowedSubstrings []string
+
+	// DenyList contains the lowercase common passwords which must not be used.
+	DenyList sets.String
+
+	// CompareHistory returns nil if hashedPassword is the hash of password, it's used to
+	// check the password history and is required to validate a password with history, see
+	// auth.NewPasswordPolicy which compares the hashes of every auth.PasswordHasher.
+	CompareHistory func(hashedPassword, password string) error
+}
+
+// NewPasswordPolicy returns the default password policy: 8 to 16 characters, with at least
+// an uppercase letter, a lowercase letter, a number and a special character.
+func NewPasswordPolicy() *PasswordPolicy {
+	return &PasswordPolicy{
+		MinLength:      8,
+		MaxLength:      16,
+		RequireUpper:   true,
+		RequireLower:   true,
+		RequireNumber:  true,
+		RequireSpecial: true,
+	}
+}
+
+// LoadDenyList adds the common passwords of the file path to the deny list, see ReadDenyList.
+func (p *PasswordPolicy) LoadDenyList(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	denyList, err := ReadDenyList(f)
+	if err != nil {
+		return fmt.Errorf("read deny list %s: %w", path, err)
+	}
+
+	if p.DenyList == nil {
+		p.DenyList = sets.NewString()
+	}
+	p.DenyList.Insert(denyList.UnsortedList()...)
+
+	return nil
+}
+
+// ReadDenyList reads a deny list of passwords from r, one password per line.
+// Blank lines and lines starting with '#' are skipped.
+func ReadDenyList(r io.Reader) (sets.String, error) {
+	denyList := sets.NewString()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		denyList.Insert(strings.ToLower(line))
+	}
+
+	return denyList, scanner.Err()
+}
+
+// Validate validates password against the policy and returns an error for every violated rule.
+// userInputs are also disallowed substrings, such as the username or the email of the user,
+// and history contains the hashes of the previous passwords of the user, which must not be reused.
+func (p *PasswordPolicy) Validate(fldPath *field.Path, password string, userInputs []string, history []string) field.ErrorList {
+	allErrs := field.ErrorList{}
+
+	if password == "" {
+		return append(allErrs, field.Required(fldPath, ""))
+	}
+
+	invalid := func(detail string) {
+		allErrs = append(allErrs, field.Invalid(fldPath, redactedPassword, detail))
+	}
+
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength || (p.MaxLength > 0 && length > p.MaxLength) {
+		if p.MaxLength > 0 {
+			invalid(fmt.Sprintf("must be between %d and %d characters long", p.MinLength, p.MaxLength))
+		} else {
+			invalid(fmt.Sprintf("must be at least %d characters long", p.MinLength))
+		}
+	}
+
+	var hasUpper, hasLower, hasNumber, hasSpecial bool
+	for _, ch := range password {
+		switch {
+		case unicode.IsNumber(ch):
+			hasNumber = true
+		case unicode.IsUpper(ch):
+			hasUpper = true
+		case unicode.IsLower(ch):
+			hasLower = true
+		case unicode.IsPunct(ch) || unicode.IsSymbol(ch):
+			hasSpecial = true
+		}
+	}
+	if p.RequireUpper && !hasUpper {
+		invalid("must contain at least one uppercase letter")
+	}
+	if p.RequireLower && !hasLower {
+		invalid("must contain at least one lowercase letter")
+	}
+	if p.RequireNumber && !hasNumber {
+		invalid("must contain at least one number")
+	}
+	if p.RequireSpecial && !hasSpecial {
+		invalid("must contain at least one special character")
+	}
+
+	lower := strings.ToLower(password)
+	for _, s := range append(append([]string{}, p.DisallowedSubstrings...), userInputs...) {
+		if s != "" && strings.Contains(lower, strings.ToLower(s)) {
+			invalid(fmt.Sprintf("must not contain %q", s))
+		}
+	}
+
+	if p.DenyList.Has(lower) {
+		invalid("is too common")
+	}
+
+	if len(history) > 0 && p.CompareHistory == nil {
+		return append(allErrs, field.InternalError(fldPath, errors.New("no comparer of the password history")))
+	}
+	for _, hashedPassword := range history {
+		if p.CompareHistory(hashedPassword, password) == nil {
+			invalid("must not be a previously used password")
+			break
+		}
+	}
+
+	return allErrs
+}
@@ -0,0 +1,120 @@
+package validation
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/xs0910/iam/pkg/component-base/validation/field"
+)
+
+func TestIsValidPassword(t *testing.T) {
+	tests := []struct {
+		password string
+		valid    bool
+	}{
+		{"Passw0rd!", true},
+		{"Passw0rd!Passw0rd!", false},
+		{"Pa0!", false},
+		{"password", false},
+		{"", false},
+	}
+
+	for _, tt := range tests {
+		if err := IsValidPassword(tt.password); (err == nil) != tt.valid {
+			t.Errorf("IsValidPassword(%q): got %v, want valid %v", tt.password, err, tt.valid)
+		}
+	}
+}
+
+func TestPasswordPolicyValidate(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("Colin@2021"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPasswordPolicy()
+	p.CompareHistory = func(hashedPassword, password string) error {
+		return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(password))
+	}
+	p.DisallowedSubstrings = []string{"iam"}
+	p.DenyList, err = ReadDenyList(strings.NewReader("# common passwords\n\nP@ssw0rd\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		details  []string
+	}{
+		{"valid", "Xk9#mPq2", nil},
+		{"every rule", "colin", []string{
+			"must be between 8 and 16 characters long",
+			"must contain at least one uppercase letter",
+			"must contain at least one number",
+			"must contain at least one special character",
+			`must not contain "Colin"`,
+		}},
+		{"disallowed substring", "myIAM#2022", []string{`must not contain "iam"`}},
+		{"deny list", "p@ssw0rd", []string{
+			"must contain at least one uppercase letter",
+			"is too common",
+		}},
+		{"history", "Colin@2021", []string{
+			`must not contain "Colin"`,
+			"must not be a previously used password",
+		}},
+	}
+
+	fldPath := field.NewPath("password")
+	for _, tt := range tests {
+		errs := p.Validate(fldPath, tt.password, []string{"Colin"}, []string{string(hash)})
+		if len(errs) != len(tt.details) {
+			t.Errorf("%s: got errors %v, want %v", tt.name, errs, tt.details)
+			continue
+		}
+
+		for i, err := range errs {
+			if err.Field != "password" || err.Detail != tt.details[i] {
+				t.Errorf("%s: got error %v, want detail %q", tt.name, err, tt.details[i])
+			}
+			if err.BadValue == tt.password {
+				t.Errorf("%s: password echoed in %v", tt.name, err)
+			}
+		}
+	}
+}
+
+func TestPasswordPolicyValidateHistoryWithoutComparer(t *testing.T) {
+	errs := NewPasswordPolicy().Validate(field.NewPath("password"), "Xk9#mPq2", nil, []string{"$2a$10$hash"})
+	if len(errs) != 1 || errs[0].Type != field.ErrorTypeInternal {
+		t.Errorf("got errors %v", errs)
+	}
+}
+
+func TestPasswordPolicyLoadDenyList(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "deny.txt")
+	if err := os.WriteFile(path, []byte("Qwerty123!\n123456\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewPasswordPolicy()
+	if err := p.LoadDenyList(path); err != nil {
+		t.Fatal(err)
+	}
+	if p.DenyList.Len() != 2 {
+		t.Errorf("got deny list %v", p.DenyList.List())
+	}
+
+	if errs := p.Validate(nil, "QWERTY123!", nil, nil); len(errs) != 2 {
+		t.Errorf("got errors %v", errs)
+	}
+
+	if err := p.LoadDenyList(filepath.Join(t.TempDir(), "missing.txt")); err == nil {
+		t.Error("expected error for a missing file")
+	}
+}
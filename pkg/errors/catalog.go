@@ -0,0 +1,71 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// CatalogEntry is the documentation form of a registered Coder.
+type CatalogEntry struct {
+	Code       int    `json:"code"`
+	HTTPStatus int    `json:"httpStatus"`
+	Message    string `json:"message"`
+	Reference  string `json:"reference,omitempty"`
+}
+
+// Catalog converts the given coders into catalog entries.
+// If no coders are given, all the registered coders are used.
+func Catalog(coders ...Coder) []CatalogEntry {
+	if len(coders) == 0 {
+		coders = Coders()
+	}
+
+	entries := make([]CatalogEntry, 0, len(coders))
+	for _, coder := range coders {
+		entries = append(entries, CatalogEntry{
+			Code:       coder.Code(),
+			HTTPStatus: coder.HTTPStatus(),
+			Message:    coder.String(),
+			Reference:  coder.Reference(),
+		})
+	}
+
+	return entries
+}
+
+// WriteCatalogJSON writes the error code catalog of coders to w as an indented JSON array.
+func WriteCatalogJSON(w io.Writer, coders ...Coder) error {
+	data, err := json.MarshalIndent(Catalog(coders...), "", "  ")
+	if err != nil {
+		return err
+	}
+
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// WriteCatalogMarkdown writes the error code catalog of coders to w as a Markdown table.
+func WriteCatalogMarkdown(w io.Writer, coders ...Coder) error {
+	var b strings.Builder
+
+	b.WriteString("| Code | HTTP Status | Message | Reference |\n")
+	b.WriteString("| ---- | ----------- | ------- | --------- |\n")
+	for _, entry := range Catalog(coders...) {
+		ref := ""
+		if entry.Reference != "" {
+			ref = fmt.Sprintf("[link](%s)", entry.Reference)
+		}
+		fmt.Fprintf(&b, "| %d | %d | %s | %s |\n",
+			entry.Code, entry.HTTPStatus, escapeMarkdownCell(entry.Message), ref)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// escapeMarkdownCell makes s safe to be placed inside a Markdown table cell.
+func escapeMarkdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
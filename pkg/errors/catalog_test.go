@@ -0,0 +1,69 @@
+package errors
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestCoders(t *testing.T) {
+	coders := Coders()
+	for i := 1; i < len(coders); i++ {
+		if coders[i-1].Code() >= coders[i].Code() {
+			t.Fatalf("Coders(): not sorted by code: %d before %d", coders[i-1].Code(), coders[i].Code())
+		}
+	}
+
+	for _, code := range []int{ConfigurationNotValid, ErrInvalidJSON, ErrEOF, ErrLoadConfigFailed} {
+		found := false
+		for _, coder := range coders {
+			if coder.Code() == code {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("Coders(): registered code %d is missing", code)
+		}
+	}
+}
+
+func TestWriteCatalogMarkdown(t *testing.T) {
+	var buf bytes.Buffer
+	coders := []Coder{
+		NewCoder(110001, 404, "User not found", "https://example.com/errors#110001"),
+		NewCoder(110002, 400, "Name a|b is invalid", ""),
+	}
+
+	if err := WriteCatalogMarkdown(&buf, coders...); err != nil {
+		t.Fatalf("WriteCatalogMarkdown(): %v", err)
+	}
+
+	want := []string{
+		"| Code | HTTP Status | Message | Reference |",
+		"| ---- | ----------- | ------- | --------- |",
+		"| 110001 | 404 | User not found | [link](https://example.com/errors#110001) |",
+		"| 110002 | 400 | Name a\\|b is invalid |  |",
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("WriteCatalogMarkdown():\n got: %q\nwant: %q", got, want)
+	}
+}
+
+func TestWriteCatalogJSON(t *testing.T) {
+	var buf bytes.Buffer
+	if err := WriteCatalogJSON(&buf, NewCoder(110001, 0, "User not found", "")); err != nil {
+		t.Fatalf("WriteCatalogJSON(): %v", err)
+	}
+
+	var entries []CatalogEntry
+	if err := json.Unmarshal(buf.Bytes(), &entries); err != nil {
+		t.Fatalf("WriteCatalogJSON(): invalid JSON: %v", err)
+	}
+
+	want := CatalogEntry{Code: 110001, HTTPStatus: 500, Message: "User not found"}
+	if len(entries) != 1 || entries[0] != want {
+		t.Errorf("WriteCatalogJSON(): got: %+v, want: [%+v]", entries, want)
+	}
+}
@@ -3,6 +3,7 @@ package errors
 import (
 	"fmt"
 	"net/http"
+	"sort"
 	"sync"
 )
 
@@ -55,12 +56,19 @@ func (coder defaultCoder) Code() int {
 	return coder.C
 }
 
+// NewCoder returns a Coder with the given code, HTTP status, external (user)
+// facing error text and reference document.
+func NewCoder(code int, httpStatus int, ext string, ref string) Coder {
+	return defaultCoder{C: code, HTTP: httpStatus, Ext: ext, Ref: ref}
+}
+
 // codes contains a map of error codes to metadata.
 var codes = map[int]Coder{}
 var codeMux = &sync.Mutex{}
 
 // Register a user define error code.
-// It will override to exist code.
+// It will override to exist code, and panic when the code is in a code range reserved
+// by a module, which registers it with MustRegisterModule.
 func Register(coder Coder) {
 	if coder.Code() == 0 {
 		panic("code `0` is reserved by `github.com/xs0910/iam/pkg/errors` as unknownCode error code")
@@ -68,11 +76,14 @@ func Register(coder Coder) {
 
 	codeMux.Lock()
 	defer codeMux.Unlock()
+
+	mustNotBeReserved(coder.Code())
 	codes[coder.Code()] = coder
 }
 
 // MustRegister register a user define error code.
-// It will panic when the same Code already exist.
+// It will panic when the same Code already exist, or when the code is in a code range
+// reserved by a module, which registers it with MustRegisterModule.
 func MustRegister(coder Coder) {
 	if coder.Code() == 0 {
 		panic("code `0` is reserved by `github.com/xs0910/iam/pkg/errors` as unknownCode error code")
@@ -81,6 +92,8 @@ func MustRegister(coder Coder) {
 	codeMux.Lock()
 	defer codeMux.Unlock()
 
+	mustNotBeReserved(coder.Code())
+
 	if _, ok := codes[coder.Code()]; ok {
 		panic(fmt.Sprintf("code: %d already exist", coder.Code()))
 	}
@@ -88,35 +101,87 @@ func MustRegister(coder Coder) {
 	codes[coder.Code()] = coder
 }
 
+// mustNotBeReserved panics when code is in a reserved code range. The caller must hold codeMux.
+func mustNotBeReserved(code int) {
+	if owner := rangeOwner(code); owner != "" {
+		panic(fmt.Sprintf("code: %d is reserved by module %s, use MustRegisterModule", code, owner))
+	}
+}
+
+// Coders returns all the registered coders sorted by code.
+func Coders() []Coder {
+	codeMux.Lock()
+	defer codeMux.Unlock()
+
+	list := make([]Coder, 0, len(codes))
+	for _, coder := range codes {
+		list = append(list, coder)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Code() < list[j].Code() })
+
+	return list
+}
+
 // ParseCoder parse any error into *withCode.
 // nil error will return nil direct.
-// None withStack error will be parsed as ErrUnknown.
+// The first *withCode found in the error chain, including the errors wrapped
+// by fmt.Errorf("%w") and the members of an Aggregate, decides the coder.
+// None withCode error will be parsed as ErrUnknown.
 func ParseCoder(err error) Coder {
 	if err == nil {
 		return nil
 	}
 
-	if v, ok := err.(*withCode); ok {
-		if coder, ok := codes[v.code]; ok {
-			return coder
+	var coder Coder = unknownCoder
+	walk(err, func(e error) bool {
+		v, ok := e.(*withCode)
+		if !ok {
+			return false
 		}
-	}
 
-	return unknownCoder
+		if c, ok := codes[v.code]; ok {
+			coder = c
+		}
+		return true
+	})
+
+	return coder
 }
 
 // IsCode reports whether any error in errs chain contains the given error code.
 func IsCode(err error, code int) bool {
-	if v, ok := err.(*withCode); ok {
-		if v.code == code {
+	return walk(err, func(e error) bool {
+		v, ok := e.(*withCode)
+		return ok && v.code == code
+	})
+}
+
+// walk calls f for err and every error in its chain until f returns true.
+// The members of an Aggregate are walked in order.
+func walk(err error, f func(error) bool) bool {
+	for err != nil {
+		if f(err) {
 			return true
 		}
 
-		if v.cause != nil {
-			return IsCode(v.cause, code)
+		switch e := err.(type) {
+		case Aggregate:
+			for _, member := range e.Errors() {
+				if walk(member, f) {
+					return true
+				}
+			}
+			return false
+		case interface{ Unwrap() []error }:
+			for _, member := range e.Unwrap() {
+				if walk(member, f) {
+					return true
+				}
+			}
+			return false
 		}
 
-		return false
+		err = Unwrap(err)
 	}
 
 	return false
@@ -180,6 +180,13 @@ func (w *withCode) Cause() error { return w.cause }
 // Unwrap provides compatibility for Go 1.13 error chains.
 func (w *withCode) Unwrap() error { return w.cause }
 
+// Is reports whether target is a withCode error carrying the same code,
+// so errors.Is(err, WithCode(code, "")) matches any error in err's chain with code.
+func (w *withCode) Is(target error) bool {
+	t, ok := target.(*withCode)
+	return ok && t.code == w.code
+}
+
 type withStack struct {
 	error
 	*stack
@@ -210,13 +217,7 @@ func WithStack(err error) error {
 func (w *withStack) Cause() error { return w.error }
 
 // Unwrap provides compatibility for Go 1.13 error chains.
-func (w *withStack) Unwrap() error {
-	if e, ok := w.error.(interface{ Unwrap() error }); ok {
-		return e.Unwrap()
-	}
-
-	return w.error
-}
+func (w *withStack) Unwrap() error { return w.error }
 
 func (w *withStack) Format(s fmt.State, verb rune) {
 	switch verb {
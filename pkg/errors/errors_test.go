@@ -307,3 +307,65 @@ func TestParseCoder(t *testing.T) {
 		}
 	}
 }
+
+func TestParseCoderChain(t *testing.T) {
+	tests := []struct {
+		err      error
+		wantCode int
+	}{
+		{WithCode(ErrEOF, "eof"), ErrEOF},
+		{fmt.Errorf("read config: %w", WithCode(ErrEOF, "eof")), ErrEOF},
+		{Wrap(fmt.Errorf("read config: %w", WithCode(ErrEOF, "eof")), "load"), ErrEOF},
+		{WithMessage(WithCode(ErrInvalidJSON, "json"), "decode"), ErrInvalidJSON},
+		{WithStack(fmt.Errorf("wrapped: %w", WrapC(io.EOF, ErrLoadConfigFailed, "load"))), ErrLoadConfigFailed},
+		{NewAggregate([]error{io.EOF, fmt.Errorf("%w", WithCode(ErrEOF, "eof"))}), ErrEOF},
+		{fmt.Errorf("%w", NewAggregate([]error{io.EOF, WithCode(ErrInvalidJSON, "json")})), ErrInvalidJSON},
+		{Wrap(io.EOF, "no code"), unknownCoder.Code()},
+		{NewAggregate([]error{io.EOF, New("no code")}), unknownCoder.Code()},
+	}
+
+	for i, tt := range tests {
+		if got := ParseCoder(tt.err).Code(); got != tt.wantCode {
+			t.Errorf("ParseCoder(%d): got %d, want: %d", i, got, tt.wantCode)
+		}
+
+		if tt.wantCode != unknownCoder.Code() && !IsCode(tt.err, tt.wantCode) {
+			t.Errorf("IsCode(%d, %d): got false, want: true", i, tt.wantCode)
+		}
+	}
+}
+
+func TestIsAs(t *testing.T) {
+	err := fmt.Errorf("outer: %w", Wrap(WrapC(io.EOF, ErrEOF, "read"), "load"))
+
+	if !Is(err, io.EOF) {
+		t.Errorf("Is(err, io.EOF): got false, want: true")
+	}
+
+	if !Is(err, WithCode(ErrEOF, "")) {
+		t.Errorf("Is(err, WithCode(ErrEOF)): got false, want: true")
+	}
+
+	if Is(err, WithCode(ErrInvalidJSON, "")) {
+		t.Errorf("Is(err, WithCode(ErrInvalidJSON)): got true, want: false")
+	}
+
+	var wc *withCode
+	if !As(err, &wc) || wc.code != ErrEOF {
+		t.Errorf("As(err, *withCode): got %v, want code %d", wc, ErrEOF)
+	}
+
+	var wm *withMessage
+	if !As(Wrap(io.EOF, "read"), &wm) || wm.msg != "read" {
+		t.Errorf("As(Wrap(io.EOF), *withMessage): got %v, want message %q", wm, "read")
+	}
+
+	var f *fundamental
+	if !As(WithStack(WithMessage(New("origin"), "msg")), &f) || f.msg != "origin" {
+		t.Errorf("As(err, *fundamental): got %v, want message %q", f, "origin")
+	}
+
+	if Unwrap(WithStack(io.EOF)) != io.EOF {
+		t.Errorf("Unwrap(WithStack(io.EOF)): want io.EOF")
+	}
+}
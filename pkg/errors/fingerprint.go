@@ -0,0 +1,174 @@
+package errors
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// FingerprintFrames is the number of top stack frames hashed by Fingerprint.
+var FingerprintFrames = 5
+
+// closureRegexp matches the numbering of anonymous functions and the type arguments
+// of generic functions, which are not stable across builds.
+var closureRegexp = regexp.MustCompile(`\.func\d+(\.\d+)*|\[\.\.\.\]`)
+
+// Fingerprint returns a fingerprint which groups recurring errors. It hashes the code
+// of the first withCode in err's chain, registered or not, and the function names of the top stack frames of the innermost
+// error with a stack in err's chain, so the same failure at the same place always
+// gets the same fingerprint regardless of messages and line numbers.
+func Fingerprint(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	fingerprint, _, _ := fingerprint(err)
+	return fingerprint
+}
+
+// fingerprint returns the fingerprint of err, and the code and the normalized frames it hashed.
+func fingerprint(err error) (string, int, []string) {
+	code := chainCode(err)
+	frames := topFrames(err, FingerprintFrames)
+
+	h := sha1.New()
+	io.WriteString(h, strconv.Itoa(code))
+	for _, frame := range frames {
+		io.WriteString(h, "\n")
+		io.WriteString(h, frame)
+	}
+
+	return hex.EncodeToString(h.Sum(nil)), code, frames
+}
+
+// chainCode returns the code of the first withCode in err's chain, unlike ParseCoder
+// the unregistered codes are kept. It returns the code of unknownCoder if there is none.
+func chainCode(err error) int {
+	code := unknownCoder.Code()
+	walk(err, func(e error) bool {
+		v, ok := e.(*withCode)
+		if ok {
+			code = v.code
+		}
+		return ok
+	})
+
+	return code
+}
+
+// topFrames returns the normalized function names of the top n frames of the
+// innermost stack trace in err's chain.
+func topFrames(err error, n int) []string {
+	var trace StackTrace
+	walk(err, func(e error) bool {
+		if st, ok := e.(interface{ StackTrace() StackTrace }); ok {
+			trace = st.StackTrace()
+		}
+		return false
+	})
+
+	if len(trace) > n {
+		trace = trace[:n]
+	}
+
+	frames := make([]string, 0, len(trace))
+	for _, f := range trace {
+		frames = append(frames, closureRegexp.ReplaceAllString(f.name(), ".func"))
+	}
+
+	return frames
+}
+
+// ErrorGroup contains the occurrences of the errors sharing a fingerprint.
+type ErrorGroup struct {
+	Fingerprint string    `json:"fingerprint"`
+	Code        int       `json:"code"`
+	Message     string    `json:"message"`
+	Frames      []string  `json:"frames"`
+	Count       int64     `json:"count"`
+	FirstSeen   time.Time `json:"firstSeen"`
+	LastSeen    time.Time `json:"lastSeen"`
+}
+
+// ErrorGroups counts the occurrences of errors per fingerprint in process.
+type ErrorGroups struct {
+	mux    sync.Mutex
+	groups map[string]*ErrorGroup
+	now    func() time.Time
+}
+
+// NewErrorGroups returns an empty ErrorGroups.
+func NewErrorGroups() *ErrorGroups {
+	return &ErrorGroups{
+		groups: map[string]*ErrorGroup{},
+		now:    time.Now,
+	}
+}
+
+// Record counts an occurrence of err and returns its fingerprint.
+// The message of a group is the message of the first recorded error.
+func (g *ErrorGroups) Record(err error) string {
+	if err == nil {
+		return ""
+	}
+
+	fp, code, frames := fingerprint(err)
+	now := g.now()
+
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	group, ok := g.groups[fp]
+	if !ok {
+		group = &ErrorGroup{
+			Fingerprint: fp,
+			Code:        code,
+			Message:     err.Error(),
+			Frames:      frames,
+			FirstSeen:   now,
+		}
+		g.groups[fp] = group
+	}
+	group.Count++
+	group.LastSeen = now
+
+	return fp
+}
+
+// Groups returns a copy of the error groups, the most frequent first.
+func (g *ErrorGroups) Groups() []ErrorGroup {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	list := make([]ErrorGroup, 0, len(g.groups))
+	for _, group := range g.groups {
+		list = append(list, *group)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Count != list[j].Count {
+			return list[i].Count > list[j].Count
+		}
+		return list[i].Fingerprint < list[j].Fingerprint
+	})
+
+	return list
+}
+
+// Reset removes all the error groups.
+func (g *ErrorGroups) Reset() {
+	g.mux.Lock()
+	defer g.mux.Unlock()
+
+	g.groups = map[string]*ErrorGroup{}
+}
+
+// MarshalJSON implements json.Marshaler, the groups are exported as returned by Groups.
+func (g *ErrorGroups) MarshalJSON() ([]byte, error) {
+	return json.Marshal(g.Groups())
+}
@@ -0,0 +1,106 @@
+package errors
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func failAt(msg string) error {
+	return WrapC(New(msg), ErrEOF, "read %s", msg)
+}
+
+func failElsewhere(msg string) error {
+	return WrapC(New(msg), ErrEOF, "read %s", msg)
+}
+
+func failWith(code int) error {
+	return WithCode(code, "failed")
+}
+
+func TestFingerprint(t *testing.T) {
+	a := failAt("config.yaml")
+	b := fmt.Errorf("load: %w", failAt("other.yaml"))
+
+	if Fingerprint(a) != Fingerprint(b) {
+		t.Errorf("Fingerprint(): same code and place, got different fingerprints")
+	}
+
+	if Fingerprint(a) == Fingerprint(failElsewhere("config.yaml")) {
+		t.Errorf("Fingerprint(): different places, got the same fingerprint")
+	}
+
+	if Fingerprint(a) == Fingerprint(WrapC(New("x"), ErrInvalidJSON, "x")) {
+		t.Errorf("Fingerprint(): different codes, got the same fingerprint")
+	}
+
+	// the unregistered codes are not collapsed into the unknown code.
+	if Fingerprint(failWith(990101)) == Fingerprint(failWith(990102)) {
+		t.Errorf("Fingerprint(): different unregistered codes, got the same fingerprint")
+	}
+
+	if Fingerprint(nil) != "" {
+		t.Errorf("Fingerprint(nil): want empty string")
+	}
+
+	frames := topFrames(a, 2)
+	if len(frames) != 2 || !strings.HasSuffix(frames[0], "errors.failAt") {
+		t.Errorf("topFrames(): got %v, want failAt on top", frames)
+	}
+
+	closure := func() error { return New("closure") }
+	if frames := topFrames(closure(), 1); !strings.HasSuffix(frames[0], "TestFingerprint.func") {
+		t.Errorf("topFrames(): got %v, want normalized closure name", frames)
+	}
+}
+
+func TestErrorGroups(t *testing.T) {
+	start := time.Date(2022, 1, 29, 0, 0, 0, 0, time.UTC)
+	now := start
+
+	groups := NewErrorGroups()
+	groups.now = func() time.Time { return now }
+
+	fp := groups.Record(failAt("a"))
+	now = now.Add(time.Minute)
+	groups.Record(failAt("b"))
+	groups.Record(io.EOF)
+	groups.Record(nil)
+
+	got := groups.Groups()
+	if len(got) != 2 {
+		t.Fatalf("Groups(): got %d groups, want: 2", len(got))
+	}
+
+	first := got[0]
+	if first.Fingerprint != fp || first.Count != 2 || first.Code != ErrEOF || first.Message != "End of input" ||
+		!first.FirstSeen.Equal(start) || !first.LastSeen.Equal(start.Add(time.Minute)) {
+		t.Errorf("Groups(): unexpected group %+v", first)
+	}
+
+	data, err := json.Marshal(groups)
+	if err != nil {
+		t.Fatalf("json.Marshal(): %v", err)
+	}
+	var decoded []ErrorGroup
+	if err := json.Unmarshal(data, &decoded); err != nil || len(decoded) != 2 || decoded[0].Count != 2 {
+		t.Errorf("json.Marshal(): got %s, %v", data, err)
+	}
+
+	groups.Reset()
+	if len(groups.Groups()) != 0 {
+		t.Errorf("Reset(): groups are not removed")
+	}
+}
+
+func TestErrorGroupsUnregisteredCode(t *testing.T) {
+	groups := NewErrorGroups()
+	groups.Record(failWith(990101))
+
+	if got := groups.Groups(); len(got) != 1 || got[0].Code != 990101 {
+		t.Errorf("Groups(): got %+v, want code 990101", got)
+	}
+}
@@ -142,13 +142,22 @@ func format(k int, jsonData []map[string]interface{}, str *bytes.Buffer, info *f
 }
 
 // list will convert the error stack into a simple array.
+// The error wrapped by a withStack is skipped when it wraps another error, e.g. the
+// withMessage of Wrap, since the withStack already carries its message.
 func list(e error) []error {
 	var ret []error
 
 	if e != nil {
 		if w, ok := e.(interface{ Unwrap() error }); ok {
+			next := w.Unwrap()
+			if ws, ok := e.(*withStack); ok {
+				if inner, ok := ws.error.(interface{ Unwrap() error }); ok {
+					next = inner.Unwrap()
+				}
+			}
+
 			ret = append(ret, e)
-			ret = append(ret, list(w.Unwrap())...)
+			ret = append(ret, list(next)...)
 		} else {
 			ret = append(ret, e)
 		}
@@ -1,6 +1,7 @@
 package errors
 
 import (
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
@@ -521,3 +522,23 @@ func testGenericRecursive(t *testing.T, beforeErr error, beforeWant []string, li
 		}
 	}
 }
+
+func TestFormatDetailEntries(t *testing.T) {
+	err := WrapC(Wrap(New("base"), "outer"), 1, "coded")
+
+	var entries []map[string]interface{}
+	if e := json.Unmarshal([]byte(fmt.Sprintf("%#+v", err)), &entries); e != nil {
+		t.Fatalf("%%#+v: %v", e)
+	}
+
+	want := []struct{ err, caller string }{{"coded", "#2 "}, {"outer", "#1 "}, {"base", "#0 "}}
+	if len(entries) != len(want) {
+		t.Fatalf("%%#+v: got %d entries %v, want %d", len(entries), entries, len(want))
+	}
+	for i, w := range want {
+		caller, _ := entries[i]["caller"].(string)
+		if entries[i]["error"] != w.err || !strings.HasPrefix(caller, w.caller) {
+			t.Errorf("%%#+v: got entry %d %v, want error %q caller %q", i, entries[i], w.err, w.caller)
+		}
+	}
+}
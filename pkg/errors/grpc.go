@@ -0,0 +1,159 @@
+package errors
+
+import (
+	"net/http"
+	"strconv"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// GRPCErrorDomain is the domain of the ErrorInfo detail attached to gRPC statuses
+// converted from withCode errors.
+const GRPCErrorDomain = "github.com/xs0910/iam"
+
+// Metadata keys of the ErrorInfo detail.
+const (
+	grpcMetadataCode      = "code"
+	grpcMetadataReference = "reference"
+)
+
+// GRPCCoder is a Coder which specifies the gRPC code that should be used for the
+// associated error code. Coders which do not implement it are mapped to a gRPC code
+// from their HTTP status.
+type GRPCCoder interface {
+	Coder
+	// GRPCCode that should be used for the associated error code.
+	GRPCCode() grpccodes.Code
+}
+
+type grpcCoder struct {
+	defaultCoder
+
+	// GRPC code that should be used for the associated error code.
+	GRPC grpccodes.Code
+}
+
+func (coder grpcCoder) GRPCCode() grpccodes.Code {
+	return coder.GRPC
+}
+
+// NewGRPCCoder returns a Coder like NewCoder which also carries the gRPC code.
+func NewGRPCCoder(code int, httpStatus int, grpcCode grpccodes.Code, ext string, ref string) Coder {
+	return grpcCoder{
+		defaultCoder: defaultCoder{C: code, HTTP: httpStatus, Ext: ext, Ref: ref},
+		GRPC:         grpcCode,
+	}
+}
+
+// GRPCCode returns the gRPC code of coder.
+func GRPCCode(coder Coder) grpccodes.Code {
+	if c, ok := coder.(GRPCCoder); ok {
+		return c.GRPCCode()
+	}
+
+	switch coder.HTTPStatus() {
+	case http.StatusOK:
+		return grpccodes.OK
+	case http.StatusBadRequest:
+		return grpccodes.InvalidArgument
+	case http.StatusUnauthorized:
+		return grpccodes.Unauthenticated
+	case http.StatusForbidden:
+		return grpccodes.PermissionDenied
+	case http.StatusNotFound:
+		return grpccodes.NotFound
+	case http.StatusConflict:
+		return grpccodes.AlreadyExists
+	case http.StatusPreconditionFailed:
+		return grpccodes.FailedPrecondition
+	case http.StatusRequestedRangeNotSatisfiable:
+		return grpccodes.OutOfRange
+	case http.StatusTooManyRequests:
+		return grpccodes.ResourceExhausted
+	case 499:
+		return grpccodes.Canceled
+	case http.StatusNotImplemented:
+		return grpccodes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return grpccodes.Unavailable
+	case http.StatusGatewayTimeout:
+		return grpccodes.DeadlineExceeded
+	}
+
+	return grpccodes.Internal
+}
+
+// GRPCStatus returns the gRPC status of the withCode error, it allows the errors
+// returned by gRPC handlers to be converted by google.golang.org/grpc/status.FromError.
+func (w *withCode) GRPCStatus() *status.Status {
+	return ToGRPCStatus(w)
+}
+
+// ToGRPCStatus converts err into a gRPC status carrying the business code and the
+// reference of the coder parsed from err in an ErrorInfo detail.
+// nil error will return nil direct.
+func ToGRPCStatus(err error) *status.Status {
+	if err == nil {
+		return nil
+	}
+
+	coder := ParseCoder(err)
+	st := status.New(GRPCCode(coder), coder.String())
+
+	metadata := map[string]string{grpcMetadataCode: strconv.Itoa(coder.Code())}
+	if coder.Reference() != "" {
+		metadata[grpcMetadataReference] = coder.Reference()
+	}
+
+	detailed, derr := st.WithDetails(&errdetails.ErrorInfo{
+		Reason:   strconv.Itoa(coder.Code()),
+		Domain:   GRPCErrorDomain,
+		Metadata: metadata,
+	})
+	if derr != nil {
+		return st
+	}
+
+	return detailed
+}
+
+// FromGRPCStatus converts a gRPC status back into a withCode error.
+// The business code is restored from the ErrorInfo detail added by ToGRPCStatus,
+// a status without it is parsed as ErrUnknown. An OK status will return nil.
+func FromGRPCStatus(st *status.Status) error {
+	if st == nil || st.Code() == grpccodes.OK {
+		return nil
+	}
+
+	code := unknownCoder.Code()
+	for _, detail := range st.Details() {
+		info, ok := detail.(*errdetails.ErrorInfo)
+		if !ok || info.GetDomain() != GRPCErrorDomain {
+			continue
+		}
+
+		if c, err := strconv.Atoi(info.GetMetadata()[grpcMetadataCode]); err == nil {
+			code = c
+		}
+		break
+	}
+
+	return WithCode(code, "%s", st.Message())
+}
+
+// FromGRPCError converts an error returned by a gRPC client into a withCode error.
+// nil error will return nil direct.
+func FromGRPCError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	st, ok := status.FromError(err)
+	if !ok {
+		return WrapC(err, unknownCoder.Code(), "%s", err.Error())
+	}
+
+	return FromGRPCStatus(st)
+}
@@ -0,0 +1,74 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+
+	grpccodes "google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const ErrGRPCNotFound int = 1100
+
+func init() {
+	Register(NewGRPCCoder(ErrGRPCNotFound, 404, grpccodes.NotFound, "Resource not found", "https://example.com/errors#1100"))
+}
+
+func TestGRPCCode(t *testing.T) {
+	tests := []struct {
+		coder Coder
+		want  grpccodes.Code
+	}{
+		{NewGRPCCoder(1, 400, grpccodes.FailedPrecondition, "", ""), grpccodes.FailedPrecondition},
+		{NewCoder(1, 400, "", ""), grpccodes.InvalidArgument},
+		{NewCoder(1, 401, "", ""), grpccodes.Unauthenticated},
+		{NewCoder(1, 404, "", ""), grpccodes.NotFound},
+		{NewCoder(1, 0, "", ""), grpccodes.Internal},
+		{unknownCoder, grpccodes.Internal},
+	}
+
+	for i, tt := range tests {
+		if got := GRPCCode(tt.coder); got != tt.want {
+			t.Errorf("GRPCCode(%d): got %v, want: %v", i, got, tt.want)
+		}
+	}
+}
+
+func TestGRPCStatusRoundTrip(t *testing.T) {
+	tests := []struct {
+		err      error
+		wantCode grpccodes.Code
+		wantMsg  string
+		wantBiz  int
+	}{
+		{WithCode(ErrGRPCNotFound, "user foo"), grpccodes.NotFound, "Resource not found", ErrGRPCNotFound},
+		{fmt.Errorf("get: %w", WrapC(io.EOF, ErrEOF, "read")), grpccodes.Internal, "End of input", ErrEOF},
+		{io.EOF, grpccodes.Internal, "An internal server error occurred", unknownCoder.Code()},
+	}
+
+	for i, tt := range tests {
+		st := ToGRPCStatus(tt.err)
+		if st.Code() != tt.wantCode || st.Message() != tt.wantMsg {
+			t.Errorf("ToGRPCStatus(%d): got (%v, %q), want: (%v, %q)", i, st.Code(), st.Message(), tt.wantCode, tt.wantMsg)
+		}
+
+		// simulate the gRPC hop
+		got := FromGRPCError(status.ErrorProto(st.Proto()))
+		if !IsCode(got, tt.wantBiz) {
+			t.Errorf("FromGRPCError(%d): got code %d, want: %d", i, ParseCoder(got).Code(), tt.wantBiz)
+		}
+	}
+
+	if st, ok := status.FromError(WithCode(ErrGRPCNotFound, "user foo")); !ok || st.Code() != grpccodes.NotFound {
+		t.Errorf("status.FromError(withCode): got (%v, %v), want: (%v, true)", st.Code(), ok, grpccodes.NotFound)
+	}
+
+	if FromGRPCError(nil) != nil || FromGRPCStatus(status.New(grpccodes.OK, "")) != nil {
+		t.Errorf("FromGRPCError(nil): want nil")
+	}
+
+	if got := FromGRPCError(status.Error(grpccodes.NotFound, "plain")); !IsCode(got, unknownCoder.Code()) {
+		t.Errorf("FromGRPCError(plain status): got code %d, want: %d", ParseCoder(got).Code(), unknownCoder.Code())
+	}
+}
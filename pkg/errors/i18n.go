@@ -0,0 +1,109 @@
+package errors
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+
+	"golang.org/x/text/language"
+)
+
+// DefaultLanguage is the language of the text returned by Coder.String().
+var DefaultLanguage = language.English
+
+// LocalizedCoder is a Coder which is able to translate its external (user) facing
+// error text by itself.
+type LocalizedCoder interface {
+	Coder
+	// LocalizedString returns the error text in the language tag,
+	// ok is false if there is no translation for tag.
+	LocalizedString(tag language.Tag) (text string, ok bool)
+}
+
+// messages contains the translated external error texts, keyed by code and language.
+var messages = map[int]map[language.Tag]string{}
+var messageMux = &sync.RWMutex{}
+
+// RegisterMessages registers the translations of external error texts for the language lang,
+// msgs is keyed by error code. It will override the exist translations.
+func RegisterMessages(lang string, msgs map[int]string) error {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return fmt.Errorf("invalid language tag %q: %w", lang, err)
+	}
+
+	messageMux.Lock()
+	defer messageMux.Unlock()
+
+	for code, msg := range msgs {
+		if _, ok := messages[code]; !ok {
+			messages[code] = map[language.Tag]string{}
+		}
+		messages[code][tag] = msg
+	}
+
+	return nil
+}
+
+// MustRegisterMessages is like RegisterMessages but panics if lang can not be parsed.
+func MustRegisterMessages(lang string, msgs map[int]string) {
+	if err := RegisterMessages(lang, msgs); err != nil {
+		panic(err)
+	}
+}
+
+// LocalizedString returns the external error text of coder in the language which matches
+// best with acceptLanguage, a value of the HTTP Accept-Language header, among DefaultLanguage,
+// the translations of a LocalizedCoder for the desired languages and the registered ones.
+// The translations of a LocalizedCoder override the registered ones of the same language.
+// It falls back to coder.String() when no translation is available.
+func LocalizedString(coder Coder, acceptLanguage string) string {
+	if coder == nil {
+		return ""
+	}
+
+	desired, _, err := language.ParseAcceptLanguage(acceptLanguage)
+	if err != nil || len(desired) == 0 {
+		return coder.String()
+	}
+
+	// The first supported tag is the default, it's chosen when nothing matches.
+	supported := []language.Tag{DefaultLanguage}
+	texts := []string{coder.String()}
+	seen := map[language.Tag]bool{DefaultLanguage: true}
+
+	if lc, ok := coder.(LocalizedCoder); ok {
+		for _, tag := range desired {
+			if seen[tag] {
+				continue
+			}
+			if text, ok := lc.LocalizedString(tag); ok {
+				seen[tag] = true
+				supported = append(supported, tag)
+				texts = append(texts, text)
+			}
+		}
+	}
+
+	messageMux.RLock()
+	translations := messages[coder.Code()]
+	tags := make([]language.Tag, 0, len(translations))
+	for tag := range translations {
+		if !seen[tag] {
+			tags = append(tags, tag)
+		}
+	}
+	sort.Slice(tags, func(i, j int) bool { return tags[i].String() < tags[j].String() })
+	for _, tag := range tags {
+		supported = append(supported, tag)
+		texts = append(texts, translations[tag])
+	}
+	messageMux.RUnlock()
+
+	_, index, confidence := language.NewMatcher(supported).Match(desired...)
+	if confidence == language.No {
+		return coder.String()
+	}
+
+	return texts[index]
+}
@@ -0,0 +1,55 @@
+package errors
+
+import (
+	"testing"
+
+	"golang.org/x/text/language"
+)
+
+type localizedCoder struct {
+	defaultCoder
+}
+
+func (c localizedCoder) LocalizedString(tag language.Tag) (string, bool) {
+	if base, _ := tag.Base(); base.String() == "fr" {
+		return "Erreur interne", true
+	}
+	return "", false
+}
+
+func TestLocalizedString(t *testing.T) {
+	MustRegisterMessages("zh-CN", map[int]string{ErrEOF: "输入意外结束"})
+	MustRegisterMessages("ja", map[int]string{ErrEOF: "入力の終わり"})
+
+	coder := ParseCoder(WithCode(ErrEOF, "eof"))
+	tests := []struct {
+		coder          Coder
+		acceptLanguage string
+		want           string
+	}{
+		{coder, "", "End of input"},
+		{coder, "zh-CN,zh;q=0.9,en;q=0.8", "输入意外结束"},
+		{coder, "zh", "输入意外结束"},
+		{coder, "ja-JP", "入力の終わり"},
+		{coder, "en-US,zh-CN;q=0.5", "End of input"},
+		{coder, "de", "End of input"},
+		{coder, "invalid;;q=x", "End of input"},
+		{ParseCoder(WithCode(ErrInvalidJSON, "json")), "zh-CN", "Data is not valid JSON"},
+		{localizedCoder{defaultCoder{1001, 500, "Internal error", ""}}, "fr-FR,en;q=0.5", "Erreur interne"},
+		{localizedCoder{defaultCoder{1001, 500, "Internal error", ""}}, "zh-CN", "Internal error"},
+		{localizedCoder{defaultCoder{1001, 500, "Internal error", ""}}, "en-US,fr;q=0.5", "Internal error"},
+		{localizedCoder{defaultCoder{1001, 500, "Internal error", ""}}, "de,fr;q=0.5", "Erreur interne"},
+	}
+
+	for _, tt := range tests {
+		if got := LocalizedString(tt.coder, tt.acceptLanguage); got != tt.want {
+			t.Errorf("LocalizedString(%d, %q): got: %q, want: %q", tt.coder.Code(), tt.acceptLanguage, got, tt.want)
+		}
+	}
+}
+
+func TestRegisterMessagesInvalidLanguage(t *testing.T) {
+	if err := RegisterMessages("not a language", map[int]string{ErrEOF: "x"}); err == nil {
+		t.Errorf("RegisterMessages(): expected error for invalid language tag")
+	}
+}
@@ -0,0 +1,131 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// CodeRange is a range of error codes reserved by a module, both ends included.
+type CodeRange struct {
+	Module string `json:"module"`
+	Min    int    `json:"min"`
+	Max    int    `json:"max"`
+}
+
+// Contains reports whether code is in the range.
+func (r CodeRange) Contains(code int) bool {
+	return code >= r.Min && code <= r.Max
+}
+
+func (r CodeRange) overlaps(other CodeRange) bool {
+	return r.Min <= other.Max && other.Min <= r.Max
+}
+
+// ranges contains the reserved code ranges, it's protected by codeMux.
+var ranges []CodeRange
+
+// ReserveRange reserves the codes from min to max for module. A module may reserve
+// several ranges, but ranges of different modules must not overlap.
+// It fails when a code of the range is already registered by Register or MustRegister.
+func ReserveRange(module string, min, max int) error {
+	if module == "" {
+		return fmt.Errorf("module of code range [%d, %d] must not be empty", min, max)
+	}
+
+	r := CodeRange{Module: module, Min: min, Max: max}
+	if min > max || r.Contains(0) {
+		return fmt.Errorf("invalid code range [%d, %d] of module %s", min, max, module)
+	}
+
+	codeMux.Lock()
+	defer codeMux.Unlock()
+
+	for _, reserved := range ranges {
+		if reserved.overlaps(r) {
+			return fmt.Errorf("code range [%d, %d] of module %s overlaps with [%d, %d] of module %s",
+				min, max, module, reserved.Min, reserved.Max, reserved.Module)
+		}
+	}
+
+	// the codes of the modules are in their own ranges, which don't overlap with r.
+	for code := range codes {
+		if r.Contains(code) {
+			return fmt.Errorf("code range [%d, %d] of module %s contains the registered code %d",
+				min, max, module, code)
+		}
+	}
+
+	ranges = append(ranges, r)
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i].Min < ranges[j].Min })
+
+	return nil
+}
+
+// MustReserveRange reserves the codes from min to max for module.
+// It will panic when the range is invalid or overlaps with another reserved range.
+func MustReserveRange(module string, min, max int) {
+	if err := ReserveRange(module, min, max); err != nil {
+		panic(err)
+	}
+}
+
+// Ranges returns the reserved code ranges sorted by their lower bound.
+func Ranges() []CodeRange {
+	codeMux.Lock()
+	defer codeMux.Unlock()
+
+	return append([]CodeRange(nil), ranges...)
+}
+
+// MustRegisterModule register a user define error code on behalf of module.
+// It will panic when the same Code already exist, or when the code is outside the
+// code ranges reserved by module, see ReserveRange.
+func MustRegisterModule(module string, coder Coder) {
+	if coder.Code() == 0 {
+		panic("code `0` is reserved by `github.com/xs0910/iam/pkg/errors` as unknownCode error code")
+	}
+
+	codeMux.Lock()
+	defer codeMux.Unlock()
+
+	if owner := rangeOwner(coder.Code()); owner != module {
+		if owner == "" {
+			panic(fmt.Sprintf("code: %d is outside the code ranges reserved by module %s", coder.Code(), module))
+		}
+		panic(fmt.Sprintf("code: %d is reserved by module %s, not %s", coder.Code(), owner, module))
+	}
+
+	if _, ok := codes[coder.Code()]; ok {
+		panic(fmt.Sprintf("code: %d already exist", coder.Code()))
+	}
+
+	codes[coder.Code()] = coder
+}
+
+// rangeOwner returns the module which reserved code, an empty string if code is not
+// in a reserved range. The caller must hold codeMux.
+func rangeOwner(code int) string {
+	for _, r := range ranges {
+		if r.Contains(code) {
+			return r.Module
+		}
+	}
+
+	return ""
+}
+
+// WriteRangesMarkdown writes the reserved code ranges to w as a Markdown table.
+func WriteRangesMarkdown(w io.Writer) error {
+	var b strings.Builder
+
+	b.WriteString("| Module | Min | Max |\n")
+	b.WriteString("| ------ | --- | --- |\n")
+	for _, r := range Ranges() {
+		fmt.Fprintf(&b, "| %s | %d | %d |\n", escapeMarkdownCell(r.Module), r.Min, r.Max)
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
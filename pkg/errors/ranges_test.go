@@ -0,0 +1,130 @@
+package errors
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// testRegistry replaces the registered codes and the reserved ranges with a copy of
+// them, in which user and secret reserve their ranges, until the end of the test.
+func testRegistry(t *testing.T) {
+	codeMux.Lock()
+	savedCodes, savedRanges := codes, ranges
+	codes = make(map[int]Coder, len(savedCodes))
+	for code, coder := range savedCodes {
+		codes[code] = coder
+	}
+	ranges = append([]CodeRange(nil), savedRanges...)
+	codeMux.Unlock()
+
+	t.Cleanup(func() {
+		codeMux.Lock()
+		defer codeMux.Unlock()
+		codes, ranges = savedCodes, savedRanges
+	})
+
+	MustReserveRange("user", 110000, 110099)
+	MustReserveRange("secret", 110100, 110199)
+}
+
+func TestReserveRange(t *testing.T) {
+	testRegistry(t)
+	Register(NewCoder(120500, 400, "", ""))
+
+	tests := []struct {
+		module   string
+		min, max int
+		wantErr  bool
+	}{
+		{"", 120000, 120099, true},
+		{"policy", 120099, 120000, true},
+		{"policy", -10, 10, true},
+		{"policy", 110050, 110150, true},
+		{"policy", 110199, 110299, true},
+		{"user", 110200, 110299, false},
+		{"policy", 120500, 120599, true},
+	}
+
+	for _, tt := range tests {
+		err := ReserveRange(tt.module, tt.min, tt.max)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("ReserveRange(%q, %d, %d): got error %v, want error: %v", tt.module, tt.min, tt.max, err, tt.wantErr)
+		}
+	}
+
+	got := Ranges()
+	for i := 1; i < len(got); i++ {
+		if got[i-1].Min > got[i].Min {
+			t.Errorf("Ranges(): not sorted: %v", got)
+		}
+	}
+}
+
+func TestMustRegisterModule(t *testing.T) {
+	testRegistry(t)
+
+	tests := []struct {
+		module    string
+		code      int
+		wantPanic string
+	}{
+		{"user", 110001, ""},
+		{"user", 110001, "already exist"},
+		{"user", 110101, "reserved by module secret"},
+		{"user", 130001, "outside the code ranges"},
+		{"secret", 110199, ""},
+	}
+
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				r := recover()
+				if tt.wantPanic == "" && r != nil {
+					t.Errorf("MustRegisterModule(%q, %d): unexpected panic %v", tt.module, tt.code, r)
+				}
+				if tt.wantPanic != "" && (r == nil || !strings.Contains(r.(string), tt.wantPanic)) {
+					t.Errorf("MustRegisterModule(%q, %d): got panic %v, want: %q", tt.module, tt.code, r, tt.wantPanic)
+				}
+			}()
+
+			MustRegisterModule(tt.module, NewCoder(tt.code, 400, "", ""))
+		}()
+	}
+}
+
+func TestRegisterReservedCode(t *testing.T) {
+	testRegistry(t)
+
+	for name, register := range map[string]func(Coder){"Register": Register, "MustRegister": MustRegister} {
+		func() {
+			defer func() {
+				if r := recover(); r == nil || !strings.Contains(r.(string), "reserved by module user") {
+					t.Errorf("%s(110002): got panic %v, want: %q", name, r, "reserved by module user")
+				}
+			}()
+
+			register(NewCoder(110002, 400, "", ""))
+		}()
+	}
+
+	MustRegister(NewCoder(120001, 400, "", ""))
+	if _, ok := codes[110002]; ok {
+		t.Errorf("code 110002 is registered")
+	}
+}
+
+func TestWriteRangesMarkdown(t *testing.T) {
+	testRegistry(t)
+
+	var buf bytes.Buffer
+	if err := WriteRangesMarkdown(&buf); err != nil {
+		t.Fatalf("WriteRangesMarkdown(): %v", err)
+	}
+
+	for _, want := range []string{"| user | 110000 | 110099 |", "| secret | 110100 | 110199 |"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("WriteRangesMarkdown(): %q not found in %q", want, buf.String())
+		}
+	}
+}
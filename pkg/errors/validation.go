@@ -0,0 +1,48 @@
+package errors
+
+import "fmt"
+
+// validationError wraps the field errors of a failed validation, e.g. the aggregate
+// returned by field.ErrorList.ToAggregate().
+type validationError struct {
+	Aggregate
+}
+
+// Unwrap provides compatibility for Go 1.13 error chains.
+func (v *validationError) Unwrap() error { return v.Aggregate }
+
+// WithValidation returns an error with the given code which carries the field errors
+// of a failed validation, they can be retrieved with FieldErrors.
+// If fieldErrs is nil, WithValidation returns nil.
+//
+//	if errs := validation.NewValidator(user).Validate(); len(errs) != 0 {
+//		return errors.WithValidation(code.ErrValidation, errs.ToAggregate())
+//	}
+func WithValidation(code int, fieldErrs Aggregate) error {
+	if fieldErrs == nil {
+		return nil
+	}
+
+	return &withCode{
+		err:   fmt.Errorf("validation failed: %s", fieldErrs.Error()),
+		code:  code,
+		cause: &validationError{fieldErrs},
+		stack: callers(),
+	}
+}
+
+// FieldErrors returns the field errors carried by the first validation error in
+// err's chain, see WithValidation. It returns nil if there is none.
+func FieldErrors(err error) []error {
+	var v *validationError
+	if !As(err, &v) {
+		return nil
+	}
+
+	flattened := Flatten(v.Aggregate)
+	if flattened == nil {
+		return nil
+	}
+
+	return flattened.Errors()
+}
@@ -0,0 +1,40 @@
+package errors
+
+import (
+	"fmt"
+	"io"
+	"testing"
+)
+
+type fieldError struct {
+	field string
+}
+
+func (e *fieldError) Error() string { return e.field + ": Invalid value" }
+
+func TestWithValidation(t *testing.T) {
+	if WithValidation(ErrInvalidJSON, nil) != nil {
+		t.Errorf("WithValidation(nil): want nil")
+	}
+
+	fieldErrs := NewAggregate([]error{&fieldError{"name"}, NewAggregate([]error{&fieldError{"age"}})})
+	err := fmt.Errorf("create user: %w", WithValidation(ErrInvalidJSON, fieldErrs))
+
+	if got := ParseCoder(err).Code(); got != ErrInvalidJSON {
+		t.Errorf("ParseCoder(): got %d, want: %d", got, ErrInvalidJSON)
+	}
+
+	got := FieldErrors(err)
+	if len(got) != 2 || got[0].Error() != "name: Invalid value" || got[1].Error() != "age: Invalid value" {
+		t.Errorf("FieldErrors(): got %v, want: [name: Invalid value age: Invalid value]", got)
+	}
+
+	var fe *fieldError
+	if !As(got[1], &fe) || fe.field != "age" {
+		t.Errorf("As(FieldErrors()[1], *fieldError): got %v, want field %q", fe, "age")
+	}
+
+	if FieldErrors(WrapC(io.EOF, ErrEOF, "read")) != nil {
+		t.Errorf("FieldErrors(): want nil for errors without validation error")
+	}
+}
@@ -0,0 +1,336 @@
+// Codegen is a tool to automate the registration of error codes. Given the name
+// of an integer type T, codegen scans the constants of type T declared in a Go
+// package and creates a new self-contained Go source file which registers every
+// constant into github.com/xs0910/iam/pkg/errors. With --doc, it renders the same
+// constants as an error code catalog instead.
+//
+// Every constant must be documented with a comment of the form:
+//
+//	// ErrUserNotFound - 404: User not found.
+//	// Reference: https://github.com/xs0910/iam/docs/errors.md#110001
+//	ErrUserNotFound int = iota + 110001
+//
+// The first line carries the HTTP status and the external (user) facing message,
+// the optional "Reference:" line carries the reference document of the code.
+//
+// It is designed to be used with go generate:
+//
+//	//go:generate codegen --type=int
+//	//go:generate codegen --type=int --doc --output ../../../docs/guide/zh-CN/api/error_code_generated.md
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/ast"
+	"go/constant"
+	"go/format"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/spf13/pflag"
+
+	"github.com/xs0910/iam/pkg/errors"
+)
+
+var (
+	typeName = pflag.StringP("type", "t", "int", "the type of the error code constants.")
+	output   = pflag.StringP("output", "o", "", "output file name; default srcdir/<type>_generated.go, or srcdir/error_code_generated.md with --doc.")
+	doc      = pflag.BoolP("doc", "d", false, "render the error code catalog instead of the registration code.")
+	docFmt   = pflag.StringP("format", "f", "markdown", "format of the error code catalog, one of markdown or json.")
+	module   = pflag.StringP("module", "m", "", "register the codes on behalf of the module, which must reserve their code range.")
+)
+
+var (
+	commentRegexp   = regexp.MustCompile(`^(\w+) - (\d{3}): (.+?)\.?$`)
+	referenceRegexp = regexp.MustCompile(`^Reference: (\S+)$`)
+)
+
+// Usage is a replacement usage function for the flags package.
+func Usage() {
+	fmt.Fprintf(os.Stderr, "Usage of codegen:\n")
+	fmt.Fprintf(os.Stderr, "\tcodegen [flags] --type T [directory]\n")
+	fmt.Fprintf(os.Stderr, "\tcodegen [flags] --type T files... # Must be a single package\n")
+	fmt.Fprintf(os.Stderr, "Flags:\n")
+	pflag.PrintDefaults()
+}
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("codegen: ")
+	pflag.Usage = Usage
+	pflag.Parse()
+
+	args := pflag.Args()
+	if len(args) == 0 {
+		// Default: process whole package in current directory.
+		args = []string{"."}
+	}
+
+	dir := args[0]
+	if len(args) > 1 || !isDirectory(dir) {
+		dir = filepath.Dir(args[0])
+	}
+
+	outputName := *output
+	if outputName == "" {
+		baseName := fmt.Sprintf("%s_generated.go", *typeName)
+		if *doc {
+			baseName = "error_code_generated.md"
+		}
+		outputName = filepath.Join(dir, strings.ToLower(baseName))
+	}
+
+	g := &Generator{typeName: *typeName}
+	pkgName, err := g.parsePackage(args, outputName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if len(g.values) == 0 {
+		log.Fatalf("no constants of type %s found", g.typeName)
+	}
+
+	var src []byte
+	if *doc {
+		src, err = g.generateDocs()
+	} else {
+		src, err = g.generate(pkgName)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(outputName, src, 0o600); err != nil {
+		log.Fatalf("writing output: %s", err)
+	}
+}
+
+// isDirectory reports whether the named file is a directory.
+func isDirectory(name string) bool {
+	info, err := os.Stat(name)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return info.IsDir()
+}
+
+// Value represents a declared error code constant.
+type Value struct {
+	name       string
+	code       int
+	httpStatus int
+	message    string
+	reference  string
+}
+
+// Generator holds the state of the analysis.
+type Generator struct {
+	typeName string
+	values   []Value
+}
+
+// parsePackage analyzes the single package constructed from the patterns and
+// collects the annotated constants of the generator type. The file named skip
+// is ignored so that stale generated code never affects a new run.
+func (g *Generator) parsePackage(patterns []string, skip string) (string, error) {
+	filenames, err := goFiles(patterns, skip)
+	if err != nil {
+		return "", err
+	}
+
+	fset := token.NewFileSet()
+	files := make([]*ast.File, 0, len(filenames))
+	for _, name := range filenames {
+		f, err := parser.ParseFile(fset, name, nil, parser.ParseComments)
+		if err != nil {
+			return "", err
+		}
+		files = append(files, f)
+	}
+	if len(files) == 0 {
+		return "", fmt.Errorf("no buildable Go source files in %s", strings.Join(patterns, " "))
+	}
+
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	info := &types.Info{Defs: map[*ast.Ident]types.Object{}}
+	pkg, err := conf.Check(files[0].Name.Name, fset, files, info)
+	if err != nil {
+		return "", fmt.Errorf("checking package: %w", err)
+	}
+
+	for _, file := range files {
+		if err := g.collect(file, info); err != nil {
+			return "", err
+		}
+	}
+	sort.Slice(g.values, func(i, j int) bool { return g.values[i].code < g.values[j].code })
+
+	return pkg.Name(), nil
+}
+
+// goFiles returns the non-test Go files named by patterns, which are either a
+// single directory or a list of files.
+func goFiles(patterns []string, skip string) ([]string, error) {
+	var names []string
+	if len(patterns) == 1 && isDirectory(patterns[0]) {
+		matches, err := filepath.Glob(filepath.Join(patterns[0], "*.go"))
+		if err != nil {
+			return nil, err
+		}
+		names = matches
+	} else {
+		names = patterns
+	}
+
+	var filenames []string
+	for _, name := range names {
+		if strings.HasSuffix(name, "_test.go") || filepath.Clean(name) == filepath.Clean(skip) {
+			continue
+		}
+		filenames = append(filenames, name)
+	}
+
+	return filenames, nil
+}
+
+// collect records the constants of the generator type declared in file.
+func (g *Generator) collect(file *ast.File, info *types.Info) error {
+	for _, decl := range file.Decls {
+		decl, ok := decl.(*ast.GenDecl)
+		if !ok || decl.Tok != token.CONST {
+			continue
+		}
+
+		for _, spec := range decl.Specs {
+			vspec, _ := spec.(*ast.ValueSpec)
+			comment := vspec.Doc
+			if comment == nil && len(decl.Specs) == 1 {
+				comment = decl.Doc
+			}
+
+			for _, name := range vspec.Names {
+				if name.Name == "_" {
+					continue
+				}
+
+				obj, ok := info.Defs[name].(*types.Const)
+				if !ok || obj.Type().String() != g.typeName {
+					continue
+				}
+
+				v, err := parseValue(name.Name, obj, comment)
+				if err != nil {
+					return err
+				}
+				g.values = append(g.values, v)
+			}
+		}
+	}
+
+	return nil
+}
+
+// parseValue builds the Value of the constant obj from its doc comment.
+func parseValue(name string, obj *types.Const, comment *ast.CommentGroup) (Value, error) {
+	code, ok := constant.Int64Val(obj.Val())
+	if !ok {
+		return Value{}, fmt.Errorf("%s: can't happen: constant is not an integer", name)
+	}
+
+	if comment == nil {
+		return Value{}, fmt.Errorf("%s: missing comment, expected `// %s - <HTTP status>: <message>.`", name, name)
+	}
+
+	lines := strings.Split(strings.TrimSpace(comment.Text()), "\n")
+	matches := commentRegexp.FindStringSubmatch(strings.TrimSpace(lines[0]))
+	if matches == nil || matches[1] != name {
+		return Value{}, fmt.Errorf("%s: invalid comment %q, expected `// %s - <HTTP status>: <message>.`",
+			name, lines[0], name)
+	}
+
+	var httpStatus int
+	fmt.Sscanf(matches[2], "%d", &httpStatus)
+	if http.StatusText(httpStatus) == "" {
+		return Value{}, fmt.Errorf("%s: invalid HTTP status %d", name, httpStatus)
+	}
+
+	v := Value{
+		name:       name,
+		code:       int(code),
+		httpStatus: httpStatus,
+		message:    matches[3],
+	}
+	for _, line := range lines[1:] {
+		if ref := referenceRegexp.FindStringSubmatch(strings.TrimSpace(line)); ref != nil {
+			v.reference = ref[1]
+		}
+	}
+
+	return v, nil
+}
+
+// generate produces the registration code of the collected values.
+func (g *Generator) generate(pkgName string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	fmt.Fprintf(&buf, "// Code generated by \"codegen %s\"; DO NOT EDIT.\n", strings.Join(os.Args[1:], " "))
+	fmt.Fprintf(&buf, "\n")
+	fmt.Fprintf(&buf, "package %s\n", pkgName)
+	fmt.Fprintf(&buf, "\n")
+	fmt.Fprintf(&buf, "import \"github.com/xs0910/iam/pkg/errors\"\n")
+	fmt.Fprintf(&buf, "\n")
+	fmt.Fprintf(&buf, "// init register error codes defines in this source code to `github.com/xs0910/iam/pkg/errors`\n")
+	fmt.Fprintf(&buf, "func init() {\n")
+	for _, v := range g.values {
+		coder := fmt.Sprintf("errors.NewCoder(%s, %d, %q, %q)", v.name, v.httpStatus, v.message, v.reference)
+		if *module != "" {
+			fmt.Fprintf(&buf, "\terrors.MustRegisterModule(%q, %s)\n", *module, coder)
+			continue
+		}
+		fmt.Fprintf(&buf, "\terrors.MustRegister(%s)\n", coder)
+	}
+	fmt.Fprintf(&buf, "}\n")
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("internal error: invalid Go generated: %w", err)
+	}
+
+	return src, nil
+}
+
+// generateDocs renders the collected values as an error code catalog.
+func (g *Generator) generateDocs() ([]byte, error) {
+	coders := make([]errors.Coder, 0, len(g.values))
+	for _, v := range g.values {
+		coders = append(coders, errors.NewCoder(v.code, v.httpStatus, v.message, v.reference))
+	}
+
+	var buf bytes.Buffer
+	switch *docFmt {
+	case "markdown":
+		fmt.Fprintf(&buf, "# Error Codes\n\n")
+		fmt.Fprintf(&buf, "Generated by `codegen --type=%s --doc`, DO NOT EDIT.\n\n", g.typeName)
+		if err := errors.WriteCatalogMarkdown(&buf, coders...); err != nil {
+			return nil, err
+		}
+	case "json":
+		if err := errors.WriteCatalogJSON(&buf, coders...); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unsupported catalog format %q", *docFmt)
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "update the golden files of testdata")
+
+// parseSource parses the package made of the Go file src with a Generator of type int.
+func parseSource(t *testing.T, src string) (*Generator, error) {
+	dir := t.TempDir()
+	if err := ioutil.WriteFile(filepath.Join(dir, "codes.go"), []byte(src), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	g := &Generator{typeName: "int"}
+	_, err := g.parsePackage([]string{dir}, "")
+
+	return g, err
+}
+
+func TestParseComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    Value
+		wantErr string
+	}{
+		{
+			"message", "// ErrA - 404: A not found.\nconst ErrA int = 100",
+			Value{name: "ErrA", code: 100, httpStatus: 404, message: "A not found"}, "",
+		},
+		{
+			"reference", "// ErrA - 400: A is invalid\n// Reference: https://example.com/errors#100\nconst ErrA int = 100",
+			Value{name: "ErrA", code: 100, httpStatus: 400, message: "A is invalid", reference: "https://example.com/errors#100"}, "",
+		},
+		{"missing comment", "const ErrA int = 100", Value{}, "ErrA: missing comment"},
+		{"invalid comment", "// ErrA is not found.\nconst ErrA int = 100", Value{}, "ErrA: invalid comment"},
+		{"other constant", "// ErrB - 404: B not found.\nconst ErrA int = 100", Value{}, "ErrA: invalid comment"},
+		{"invalid HTTP status", "// ErrA - 999: A not found.\nconst ErrA int = 100", Value{}, "ErrA: invalid HTTP status 999"},
+		{
+			"block comment", "// ErrA - 404: A not found.\nconst (\n\tErrA int = 100\n\tErrB int = 101\n)",
+			Value{}, "ErrA: missing comment",
+		},
+	}
+
+	for _, tt := range tests {
+		g, err := parseSource(t, "package codes\n\n"+tt.src+"\n")
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: got error %v, want: %q", tt.name, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || len(g.values) != 1 || g.values[0] != tt.want {
+			t.Errorf("%s: got (%+v, %v), want: %+v", tt.name, g.values, err, tt.want)
+		}
+	}
+}
+
+func TestParseIota(t *testing.T) {
+	g := &Generator{typeName: "int"}
+	if _, err := g.parsePackage([]string{"testdata/codes"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Value{
+		{name: "ErrUserNotFound", code: 110001, httpStatus: 404, message: "User not found", reference: "https://example.com/errors#110001"},
+		{name: "ErrUserAlreadyExist", code: 110002, httpStatus: 400, message: "User already exist"},
+		{name: "ErrUserDisabled", code: 110004, httpStatus: 403, message: "User a|b is disabled"},
+		{name: "ErrSecretNotFound", code: 110101, httpStatus: 404, message: "Secret not found"},
+	}
+	if len(g.values) != len(want) {
+		t.Fatalf("got %+v, want: %+v", g.values, want)
+	}
+	for i := range want {
+		if g.values[i] != want[i] {
+			t.Errorf("value %d: got %+v, want: %+v", i, g.values[i], want[i])
+		}
+	}
+}
+
+// checkGolden compares got with the golden file name of testdata, which is updated
+// instead with -update.
+func checkGolden(t *testing.T, name string, got []byte) {
+	path := filepath.Join("testdata", name)
+	if *update {
+		if err := ioutil.WriteFile(path, got, 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("%s: got:\n%s\nwant:\n%s", name, got, want)
+	}
+}
+
+func TestGenerateGolden(t *testing.T) {
+	// the arguments are written in the header of the generated code.
+	args := os.Args
+	os.Args = []string{"codegen", "--type=int"}
+	t.Cleanup(func() { os.Args = args })
+
+	g := &Generator{typeName: "int"}
+	pkgName, err := g.parsePackage([]string{"testdata/codes"}, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	src, err := g.generate(pkgName)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "int_generated.go.golden", src)
+
+	doc, err := g.generateDocs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkGolden(t, "error_code_generated.md.golden", doc)
+
+	*module = "user"
+	t.Cleanup(func() { *module = "" })
+	if src, err = g.generate(pkgName); err != nil || !strings.Contains(string(src), `errors.MustRegisterModule("user", errors.NewCoder(ErrUserNotFound, 404,`) {
+		t.Errorf("--module: got (%s, %v)", src, err)
+	}
+}
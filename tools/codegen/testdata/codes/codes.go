@@ -0,0 +1,22 @@
+package codes
+
+// user errors.
+const (
+	// ErrUserNotFound - 404: User not found.
+	// Reference: https://example.com/errors#110001
+	ErrUserNotFound int = iota + 110001
+
+	// ErrUserAlreadyExist - 400: User already exist.
+	ErrUserAlreadyExist
+
+	_
+
+	// ErrUserDisabled - 403: User a|b is disabled.
+	ErrUserDisabled
+)
+
+// ErrSecretNotFound - 404: Secret not found.
+const ErrSecretNotFound int = 110101
+
+// notACode is not of the type of the error codes.
+const notACode = 1